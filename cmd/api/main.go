@@ -2,10 +2,22 @@ package main
 
 import (
 	"cats-social/infra/server"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout bounds how long requests in flight and queued audit events
+// get to finish once the process is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
 type Server struct {
 	port int
 
@@ -13,10 +25,29 @@ type Server struct {
 }
 
 func main() {
-	server := server.NewServer()
+	server, closeServer := server.NewServer()
+
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(fmt.Sprintf("cannot start server: %s", err))
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		log.Printf("failed to shut down server: %s", err)
+	}
 
-	err := server.ListenAndServe()
+	err = closeServer(ctx)
 	if err != nil {
-		panic(fmt.Sprintf("cannot start server: %s", err))
+		log.Printf("failed to write queued audit events: %s", err)
 	}
 }
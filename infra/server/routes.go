@@ -2,10 +2,18 @@ package server
 
 import (
 	"cats-social/internal/auth"
+	"cats-social/internal/blobstore"
+	"cats-social/internal/domain"
 	"cats-social/internal/handler"
+	"cats-social/internal/imaging"
+	"cats-social/internal/keyring"
+	"cats-social/internal/loginguard"
+	"cats-social/internal/mail"
+	"cats-social/internal/matching"
 	"cats-social/internal/repository"
 	"cats-social/internal/service"
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -33,43 +41,151 @@ func (s *Server) RegisterRoutes() http.Handler {
 	// dependency injection
 	catRepository := repository.NewCatRepository()
 	catMatchRepository := repository.NewCatMatchRepository()
+	catImageRepository := repository.NewCatImageRepository()
+	catLikeRepository := repository.NewCatLikeRepository()
+	catTraitRepository := repository.NewCatTraitRepository()
+	catPedigreeRepository := repository.NewCatPedigreeRepository()
+	breedRepository := repository.NewBreedRepository()
+	adminRepository := repository.NewAdminRepository()
 
-	catService := service.NewCatService(s.db, catRepository)
-	catMatchService := service.NewCatMatchService(s.db, catMatchRepository, catRepository)
+	variantWorker := imaging.NewWorker(s.db, catImageRepository, s.blobStore)
+	variantWorker.Start()
+
+	breedCatalog := service.NewBreedCatalog(s.db, breedRepository)
+
+	catService := service.NewCatService(s.db, catRepository, catImageRepository, catTraitRepository, catPedigreeRepository, breedCatalog, s.blobStore)
+	catMatchService := service.NewCatMatchService(s.db, catMatchRepository, catRepository, catImageRepository, catPedigreeRepository, breedCatalog, matching.NewDefaultScorer(), s.inbreedingPolicy)
+	catImageService := service.NewCatImageService(s.db, catRepository, catImageRepository, s.blobStore, variantWorker)
+	catLikeService := service.NewCatLikeService(s.db, catRepository, catLikeRepository, catImageRepository)
+
+	breedService := service.NewBreedService(s.db, breedRepository, adminRepository, breedCatalog)
+
+	adminService := service.NewAdminService(s.db, adminRepository, repository.NewSessionPg(), catImageRepository, s.blobStore)
 
 	catHandler := handler.NewCatHandler(catService)
 	catMatchHandler := handler.NewCatMatchHandler(catMatchService)
+	catImageHandler := handler.NewCatImageHandler(catImageService)
+	catLikeHandler := handler.NewCatLikeHandler(catLikeService)
+	breedHandler := handler.NewBreedHandler(breedService)
+	adminHandler := handler.NewAdminHandler(adminService)
+
+	mailer := mail.NewSenderFromEnv()
+	loginTracker := loginguard.NewTrackerFromEnv(s.db)
+	auditRecorder := s.auditWriter
+
+	authService := auth.NewAuth(repository.NewUserPg(), repository.NewSessionPg(), repository.NewApiKeyRepository(), auditRecorder)
+	authentication := authService.Authentication(s.db)
+	requireSession := authService.RequireSession()
+	requireVerifiedEmail := authService.RequireVerifiedEmail()
+	requireAdmin := authService.RequireRole(domain.UserRoleAdmin)
 
 	r := gin.Default()
+	// the client IP keys the login lockout and is kept with sessions and
+	// audit events, only proxies we run may tell it
+	if err := r.SetTrustedProxies(s.trustedProxies); err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %s", err)
+	}
 
 	// r := gin.New()
 	// r.Use(gin.Recovery())
 	// r.Use(jsonLoggerMiddleware())
 
+	r.GET("/.well-known/jwks.json", handler.HandleJWKS(keyring.Default()))
+
+	if local, ok := s.blobStore.(*blobstore.LocalStore); ok {
+		r.Static(blobstore.LocalRoute, local.Dir)
+	}
+
 	// version 1
 	apiV1 := r.Group("/v1")
 
 	// user
 	user := apiV1.Group("/user")
-	user.POST("/register", handler.HandleNewUser(s.db))
-	user.POST("/login", handler.HandleLogin(s.db))
+	user.POST("/register", handler.HandleNewUser(s.db, mailer, s.passwordChecker))
+	user.POST("/login", handler.HandleLogin(s.db, loginTracker, auditRecorder))
+	user.POST("/login/2fa", handler.HandleLoginTwoFactor(s.db, loginTracker, auditRecorder))
+	user.POST("/refresh", handler.HandleRefreshToken(s.db))
+	user.POST("/logout", authentication, requireSession, handler.HandleLogout(s.db, auditRecorder))
+	user.POST("/password/forgot", handler.HandleForgotPassword(s.db, mailer, auditRecorder))
+	user.POST("/password/reset", handler.HandleResetPassword(s.db, auditRecorder, s.passwordChecker))
+	user.GET("/verify", handler.HandleVerifyEmail(s.db))
+	user.POST("/verify/resend", authentication, requireSession, handler.HandleResendVerificationEmail(s.db, mailer))
+
+	if s.oidcProvider != nil {
+		user.GET("/oidc/start", handler.HandleOIDCStart(s.db, s.oidcProvider))
+		user.GET("/oidc/callback", handler.HandleOIDCCallback(s.db, s.oidcProvider, auditRecorder))
+	}
+
+	twoFactor := user.Group("/2fa")
+	twoFactor.Use(authentication, requireSession)
+	twoFactor.POST("/enroll", handler.HandleEnrollTwoFactor(s.db))
+	twoFactor.POST("/confirm", handler.HandleConfirmTwoFactor(s.db, auditRecorder))
+	twoFactor.POST("/disable", handler.HandleDisableTwoFactor(s.db, auditRecorder))
+
+	me := user.Group("/me")
+	me.Use(authentication, requireSession)
+	me.GET("", handler.HandleGetProfile(s.db))
+	me.PATCH("", handler.HandleUpdateProfile(s.db, mailer, auditRecorder, s.passwordChecker))
+	me.DELETE("", handler.HandleDeleteProfile(s.db, s.blobStore, auditRecorder))
+	me.GET("/security-events", handler.HandleGetSecurityEvents(s.db))
+	me.GET("/sessions", handler.HandleGetSessions(s.db))
+	me.DELETE("/sessions/others", handler.HandleRevokeOtherSessions(s.db, auditRecorder))
+	me.DELETE("/sessions/:id", handler.HandleRevokeSession(s.db, auditRecorder))
+
+	apiKeys := user.Group("/api-keys")
+	apiKeys.Use(authentication, requireSession)
+	apiKeys.POST("", handler.HandleCreateApiKey(s.db, auditRecorder))
+	apiKeys.GET("", handler.HandleGetApiKeys(s.db))
+	apiKeys.DELETE(":id", handler.HandleRevokeApiKey(s.db, auditRecorder))
 
 	// cat
 	cat := apiV1.Group("/cat")
-	cat.Use(auth.NewAuth(repository.NewUserPg()).Authentication(s.db))
-
-	cat.POST("", catHandler.CreateCat())
-	cat.GET("", catHandler.GetAllCats())
-	cat.PUT(":catId", catHandler.UpdateCat())
-	cat.DELETE(":catId", catHandler.DeleteCat())
+	cat.Use(authentication)
+
+	cat.POST("", authService.RequireScope(domain.ScopeCatsWrite), requireVerifiedEmail, catHandler.CreateCat())
+	cat.GET("", authService.RequireScope(domain.ScopeCatsRead), catHandler.GetAllCats())
+	cat.GET("/traits", authService.RequireScope(domain.ScopeCatsRead), catHandler.GetTraits())
+	cat.GET(":catId", authService.RequireScope(domain.ScopeCatsRead), catHandler.GetCat())
+	cat.GET(":catId/pedigree", authService.RequireScope(domain.ScopeCatsRead), catHandler.GetPedigree())
+	cat.PUT(":catId", authService.RequireScope(domain.ScopeCatsWrite), catHandler.UpdateCat())
+	cat.DELETE(":catId", authService.RequireScope(domain.ScopeCatsWrite), catHandler.DeleteCat())
+
+	// cat images
+	cat.GET(":catId/images", authService.RequireScope(domain.ScopeCatsRead), catImageHandler.GetCatImages())
+	cat.POST(":catId/images", authService.RequireScope(domain.ScopeCatsWrite), requireVerifiedEmail, catImageHandler.UploadCatImages())
+	cat.PUT(":catId/images/order", authService.RequireScope(domain.ScopeCatsWrite), catImageHandler.ReorderCatImages())
+	cat.DELETE(":catId/images/:imageId", authService.RequireScope(domain.ScopeCatsWrite), catImageHandler.DeleteCatImage())
+
+	// cat likes
+	cat.GET("/liked", authService.RequireScope(domain.ScopeCatsRead), catLikeHandler.GetLikedCats())
+	cat.POST(":catId/like", authService.RequireScope(domain.ScopeCatsWrite), catLikeHandler.LikeCat())
+	cat.DELETE(":catId/like", authService.RequireScope(domain.ScopeCatsWrite), catLikeHandler.UnlikeCat())
+	cat.GET(":catId/likers", authService.RequireScope(domain.ScopeCatsRead), catLikeHandler.GetCatLikers())
+	cat.GET(":catId/recommendations", authService.RequireScope(domain.ScopeMatchesRead), catMatchHandler.GetRecommendations())
 
 	// cat match
 	catMatch := cat.Group("/match")
-	catMatch.POST("", catMatchHandler.CreateCatMatch())
-	catMatch.GET("", catMatchHandler.GetCatMatchesByIssuerOrReceiverID())
-	catMatch.POST("/approve", catMatchHandler.ApproveCatMatch())
-	catMatch.POST("/reject", catMatchHandler.RejectCatMatch())
-	catMatch.DELETE(":id", catMatchHandler.DeleteCatMatchByID())
+	catMatch.POST("", authService.RequireScope(domain.ScopeMatchesWrite), requireVerifiedEmail, catMatchHandler.CreateCatMatch())
+	catMatch.GET("", authService.RequireScope(domain.ScopeMatchesRead), catMatchHandler.GetCatMatchesByIssuerOrReceiverID())
+	catMatch.POST("/approve", authService.RequireScope(domain.ScopeMatchesWrite), catMatchHandler.ApproveCatMatch())
+	catMatch.POST("/reject", authService.RequireScope(domain.ScopeMatchesWrite), catMatchHandler.RejectCatMatch())
+	catMatch.DELETE(":id", authService.RequireScope(domain.ScopeMatchesWrite), catMatchHandler.DeleteCatMatchByID())
+
+	// breeds
+	apiV1.GET("/breeds", authentication, authService.RequireScope(domain.ScopeCatsRead), breedHandler.GetBreeds())
+
+	// admin
+	admin := apiV1.Group("/admin")
+	admin.Use(authentication, requireSession, requireAdmin)
+
+	admin.GET("/users", adminHandler.GetAllUsers())
+	admin.POST("/users/:userId/disable", adminHandler.DisableUser())
+	admin.POST("/users/:userId/enable", adminHandler.EnableUser())
+	admin.DELETE("/cats/:catId", adminHandler.HardDeleteCat())
+	admin.POST("/matches/:id/cancel", adminHandler.CancelCatMatch())
+	admin.POST("/breeds", breedHandler.CreateBreed())
+	admin.PUT("/breeds/:breedId", breedHandler.UpdateBreed())
+	admin.DELETE("/breeds/:breedId", breedHandler.DeleteBreed())
 
 	return r
 }
@@ -1,12 +1,21 @@
 package server
 
 import (
+	"cats-social/internal/audit"
+	"cats-social/internal/blobstore"
+	"cats-social/internal/keyring"
+	"cats-social/internal/matching"
+	"cats-social/internal/oidc"
+	"cats-social/internal/passwordpolicy"
+	"cats-social/internal/repository"
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -16,9 +25,34 @@ import (
 type Server struct {
 	port int
 	db   *sql.DB
+
+	// oidcProvider is nil when OIDC login is not configured
+	oidcProvider     *oidc.Provider
+	passwordChecker  *passwordpolicy.Checker
+	blobStore        blobstore.BlobStore
+	inbreedingPolicy matching.InbreedingPolicy
+	auditWriter      *audit.BufferedWriter
+	// trustedProxies may set X-Forwarded-For, nil trusts nobody
+	trustedProxies []string
+}
+
+// trustedProxiesFromEnv reads TRUSTED_PROXIES, a comma separated list of
+// addresses or CIDR ranges of the proxies in front of the API. Without it
+// the client IP is the address of the connection.
+func trustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies
 }
 
-func NewServer() *http.Server {
+// NewServer also returns the function to call once the server has shut
+// down; it writes the audit events that are still queued.
+func NewServer() (*http.Server, func(ctx context.Context) error) {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
 
 	dbName := os.Getenv("DB_NAME")
@@ -39,10 +73,43 @@ func NewServer() *http.Server {
 		log.Fatal(err)
 	}
 
+	kr, err := keyring.LoadFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	keyring.SetDefault(kr)
+
+	passwordChecker, err := passwordpolicy.NewCheckerFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	blobStore, err := blobstore.NewBlobStoreFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inbreedingPolicy, err := matching.InbreedingPolicyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	NewServer := &Server{
 		port: port,
 
-		db: db,
+		db:               db,
+		passwordChecker:  passwordChecker,
+		blobStore:        blobStore,
+		inbreedingPolicy: inbreedingPolicy,
+		auditWriter:      audit.NewBufferedWriter(db, repository.NewAuditEventRepository()),
+		trustedProxies:   trustedProxiesFromEnv(),
+	}
+
+	if oidcConfig, ok := oidc.ConfigFromEnv(); ok {
+		NewServer.oidcProvider, err = oidc.NewProvider(context.Background(), oidcConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	// Declare Server config
@@ -54,5 +121,5 @@ func NewServer() *http.Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
-	return server
+	return server, NewServer.auditWriter.Close
 }
@@ -0,0 +1,148 @@
+// Package audit writes security events of user accounts to the
+// audit_events table without slowing down the request that caused them.
+package audit
+
+import (
+	"cats-social/internal/domain"
+	"cats-social/internal/repository"
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	bufferSize    = 1024
+	maxBatchSize  = 100
+	flushInterval = time.Second
+)
+
+// Recorder records audit events. Implementations must be safe for
+// concurrent use and must not block the caller.
+type Recorder interface {
+	Record(event *domain.AuditEvent)
+}
+
+// FromRequest builds an event carrying the client IP and the user agent of
+// the request, truncated like the one of a session.
+func FromRequest(ctx *gin.Context, eventType string, actorId uuid.UUID, metadata map[string]any) *domain.AuditEvent {
+	return domain.NewAuditEvent(eventType, actorId, ctx.ClientIP(), domain.TruncateUserAgent(ctx.Request.UserAgent()), metadata)
+}
+
+// BufferedWriter queues events in memory and inserts them in batches from
+// a background goroutine. When the queue is full events are dropped and
+// logged rather than holding up requests. Close writes what is still queued.
+type BufferedWriter struct {
+	db         *sql.DB
+	repository repository.AuditEventRepository
+	events     chan *domain.AuditEvent
+	done       chan struct{}
+
+	// mu guards closed, Record must not send once events is closed
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewBufferedWriter(db *sql.DB, auditEventRepository repository.AuditEventRepository) *BufferedWriter {
+	w := &BufferedWriter{
+		db:         db,
+		repository: auditEventRepository,
+		events:     make(chan *domain.AuditEvent, bufferSize),
+		done:       make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+func (w *BufferedWriter) Record(event *domain.AuditEvent) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		log.Printf("audit writer is closed, dropped %s event of %s", event.EventType, event.ActorID)
+		return
+	}
+
+	select {
+	case w.events <- event:
+	default:
+		log.Printf("audit buffer is full, dropped %s event of %s", event.EventType, event.ActorID)
+	}
+}
+
+// Close stops taking events and waits until the queued ones are written, or
+// until ctx is done. Events recorded afterwards are dropped.
+func (w *BufferedWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.events)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *BufferedWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*domain.AuditEvent, 0, maxBatchSize)
+
+	for {
+		select {
+		case event, ok := <-w.events:
+			if !ok {
+				if len(batch) > 0 {
+					w.flush(batch)
+				}
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) < maxBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		w.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush inserts the batch in one transaction. When that fails the events
+// are inserted one by one, so a single bad event or a passing failure does
+// not lose the others.
+func (w *BufferedWriter) flush(batch []*domain.AuditEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := w.repository.CreateAuditEvents(ctx, w.db, batch)
+	if err == nil {
+		return
+	}
+	log.Printf("failed to write %d audit events, writing them one by one: %s", len(batch), err)
+
+	for _, event := range batch {
+		err := w.repository.CreateAuditEvents(ctx, w.db, []*domain.AuditEvent{event})
+		if err != nil {
+			log.Printf("failed to write %s audit event of %s: %s", event.EventType, event.ActorID, err)
+		}
+	}
+}
@@ -1,46 +1,206 @@
 package auth
 
 import (
+	"cats-social/internal/audit"
 	"cats-social/internal/domain"
 	"cats-social/internal/repository"
 	"database/sql"
+	"errors"
+	"log"
+	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type AuthService interface {
 	Authentication(db *sql.DB) gin.HandlerFunc
+	RequireVerifiedEmail() gin.HandlerFunc
+	RequireRole(roles ...string) gin.HandlerFunc
+	RequireScope(scope string) gin.HandlerFunc
+	RequireSession() gin.HandlerFunc
 }
 
 type authServiceImpl struct {
 	ur repository.UserRepository
+	sr repository.SessionRepository
+	ar repository.ApiKeyRepository
+	rc audit.Recorder
 }
 
-func NewAuth(ur repository.UserRepository) AuthService {
+func NewAuth(ur repository.UserRepository, sr repository.SessionRepository, ar repository.ApiKeyRepository, rc audit.Recorder) AuthService {
 	return &authServiceImpl{
 		ur: ur,
+		sr: sr,
+		ar: ar,
+		rc: rc,
 	}
 }
 
 func (a *authServiceImpl) Authentication(db *sql.DB) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		invalidTokenErr := domain.NewUnauthenticatedError("invalid token")
+
+		if apiKey := ctx.GetHeader("X-API-Key"); apiKey != "" {
+			user, err := a.authenticateApiKey(db, apiKey)
+			if err != nil {
+				a.recordTokenRejected(ctx, uuid.Nil, "invalid api key")
+				invalidApiKeyErr := domain.NewUnauthenticatedError("invalid api key")
+				ctx.AbortWithStatusJSON(invalidApiKeyErr.Status(), invalidApiKeyErr)
+				return
+			}
+
+			ctx.Set("userData", user)
+			ctx.Next()
+			return
+		}
+
 		bearerToken := ctx.GetHeader("Authorization")
 		user := domain.NewUser()
 
 		err := user.ValidateToken(bearerToken)
 		if err != nil {
+			a.recordTokenRejected(ctx, uuid.Nil, "invalid token")
 			ctx.AbortWithStatusJSON(invalidTokenErr.Status(), invalidTokenErr)
 			return
 		}
 
-		_, err = a.ur.GetByEmail(db, user.Email)
-		if err != nil {
+		// the email claim goes stale when the user changes it, the id does not
+		dbUser, err := a.ur.GetById(db, user.Id)
+		if err != nil || dbUser.Id != user.Id {
+			a.recordTokenRejected(ctx, user.Id, "unknown user")
+			ctx.AbortWithStatusJSON(invalidTokenErr.Status(), invalidTokenErr)
+			return
+		}
+		// a role change or a disabled account invalidates issued tokens
+		if dbUser.IsDisabled() || dbUser.Role != user.Role {
+			a.recordTokenRejected(ctx, dbUser.Id, "account disabled or role changed")
+			ctx.AbortWithStatusJSON(invalidTokenErr.Status(), invalidTokenErr)
+			return
+		}
+		user.Email = dbUser.Email
+		user.Name = dbUser.Name
+		user.EmailVerifiedAt = dbUser.EmailVerifiedAt
+
+		session, err := a.sr.GetById(db, user.SessionId)
+		if err != nil || !session.IsActive() || session.UserID != dbUser.Id {
+			a.recordTokenRejected(ctx, dbUser.Id, "session revoked or expired")
 			ctx.AbortWithStatusJSON(invalidTokenErr.Status(), invalidTokenErr)
 			return
 		}
+		if time.Since(session.LastSeenAt) > domain.SessionLastSeenInterval || session.IP != ctx.ClientIP() {
+			if err := a.sr.TouchSession(db, session.ID, ctx.ClientIP()); err != nil {
+				log.Printf("failed to update session last seen: %s", err)
+			}
+		}
 
 		ctx.Set("userData", user)
 		ctx.Next()
 	}
 }
+
+func (a *authServiceImpl) recordTokenRejected(ctx *gin.Context, actorId uuid.UUID, reason string) {
+	a.rc.Record(audit.FromRequest(ctx, domain.AuditEventTokenRejected, actorId, map[string]any{
+		"reason": reason,
+		"path":   ctx.FullPath(),
+	}))
+}
+
+func (a *authServiceImpl) authenticateApiKey(db *sql.DB, plain string) (*domain.User, error) {
+	apiKey, err := a.ar.GetActiveApiKeyByHash(db, domain.HashOpaqueToken(plain))
+	if err != nil {
+		return nil, err
+	}
+
+	dbUser, err := a.ur.GetById(db, apiKey.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if dbUser.IsDisabled() {
+		return nil, errors.New("account has been disabled")
+	}
+
+	// last_used_at only needs to be roughly right, skip most writes
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) > time.Minute {
+		if err := a.ar.TouchApiKey(db, apiKey.ID); err != nil {
+			log.Printf("failed to update api key last use: %s", err)
+		}
+	}
+
+	user := domain.NewUser()
+	user.Id = dbUser.Id
+	user.Email = dbUser.Email
+	user.Name = dbUser.Name
+	user.Role = dbUser.Role
+	user.EmailVerifiedAt = dbUser.EmailVerifiedAt
+	user.ApiKeyScopes = append([]string{}, apiKey.Scopes...)
+
+	return user, nil
+}
+
+// RequireVerifiedEmail must run after Authentication.
+func (a *authServiceImpl) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		if !user.IsEmailVerified() {
+			err := domain.NewEmailNotVerifiedError("please verify your email first")
+			ctx.AbortWithStatusJSON(err.Status(), err)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireRole must run after Authentication.
+func (a *authServiceImpl) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		if !slices.Contains(roles, user.Role) {
+			err := domain.NewUnauthorizedError("you are not allowed to access this resource")
+			ctx.AbortWithStatusJSON(err.Status(), err)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireScope must run after Authentication. Requests authenticated with a
+// session token always pass.
+func (a *authServiceImpl) RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		if !user.HasScope(scope) {
+			err := domain.NewUnauthorizedError("api key is missing the " + scope + " scope")
+			ctx.AbortWithStatusJSON(err.Status(), err)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireSession must run after Authentication. It rejects API keys on
+// account management routes.
+func (a *authServiceImpl) RequireSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		if user.IsApiKeyAuthenticated() {
+			err := domain.NewUnauthorizedError("this resource cannot be accessed with an api key")
+			ctx.AbortWithStatusJSON(err.Status(), err)
+			return
+		}
+
+		ctx.Next()
+	}
+}
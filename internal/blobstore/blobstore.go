@@ -0,0 +1,76 @@
+// Package blobstore stores uploaded files and tells where they can be
+// downloaded from.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// BlobStore keeps files under slash separated keys. Implementations must be
+// safe for concurrent use.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the blob; deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL is the public address the blob can be downloaded from.
+	URL(key string) string
+}
+
+// NewBlobStoreFromEnv picks the store configured by BLOB_STORE, "local"
+// (default) or "s3".
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	switch os.Getenv("BLOB_STORE") {
+	case "s3":
+		cfg := S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		}
+		if cfg.Region == "" {
+			cfg.Region = "us-east-1"
+		}
+		if cfg.Endpoint == "" || cfg.Bucket == "" {
+			return nil, errors.New("blobstore: S3_ENDPOINT and S3_BUCKET are required")
+		}
+		return NewS3Store(cfg), nil
+	case "", "local":
+		dir := os.Getenv("LOCAL_BLOB_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		publicURL := os.Getenv("BLOB_PUBLIC_URL")
+		if publicURL == "" {
+			publicURL = "http://localhost:8080" + LocalRoute
+		}
+		return NewLocalStore(dir, publicURL), nil
+	default:
+		return nil, fmt.Errorf("blobstore: unknown BLOB_STORE %q", os.Getenv("BLOB_STORE"))
+	}
+}
+
+// validKey rejects keys that could escape the store's root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+
+	return true
+}
+
+func joinURL(base string, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
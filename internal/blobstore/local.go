@@ -0,0 +1,82 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocalRoute is where the API serves the files of a LocalStore.
+const LocalRoute = "/uploads"
+
+// LocalStore keeps blobs as files below Dir. The API serves them itself at
+// LocalRoute, publicURL has to point there.
+type LocalStore struct {
+	Dir       string
+	publicURL string
+}
+
+func NewLocalStore(dir string, publicURL string) *LocalStore {
+	return &LocalStore{
+		Dir:       dir,
+		publicURL: publicURL,
+	}
+}
+
+func (l *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if !validKey(key) {
+		return fmt.Errorf("blobstore: invalid key %q", key)
+	}
+
+	path := filepath.Join(l.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write next to the target and rename, readers never see half a file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("blobstore: invalid key %q", key)
+	}
+
+	return os.ReadFile(filepath.Join(l.Dir, filepath.FromSlash(key)))
+}
+
+func (l *LocalStore) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return fmt.Errorf("blobstore: invalid key %q", key)
+	}
+
+	err := os.Remove(filepath.Join(l.Dir, filepath.FromSlash(key)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (l *LocalStore) URL(key string) string {
+	return joinURL(l.publicURL, key)
+}
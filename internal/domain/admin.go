@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AdminActionDisableUser    = "disable_user"
+	AdminActionEnableUser     = "enable_user"
+	AdminActionHardDeleteCat  = "hard_delete_cat"
+	AdminActionCancelCatMatch = "cancel_cat_match"
+	AdminActionCreateBreed    = "create_breed"
+	AdminActionUpdateBreed    = "update_breed"
+	AdminActionDeleteBreed    = "delete_breed"
+)
+
+const (
+	AuditTargetUser     = "user"
+	AuditTargetCat      = "cat"
+	AuditTargetCatMatch = "cat_match"
+	AuditTargetBreed    = "breed"
+)
+
+type AdminActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type AdminAuditLog struct {
+	ID         uuid.UUID      `db:"id"`
+	CreatedAt  time.Time      `db:"created_at"`
+	AdminID    uuid.UUID      `db:"admin_id"`
+	Action     string         `db:"action"`
+	TargetType string         `db:"target_type"`
+	TargetID   uuid.UUID      `db:"target_id"`
+	Metadata   map[string]any `db:"metadata"`
+}
+
+func NewAdminAuditLog(adminId uuid.UUID, action string, targetType string, targetId uuid.UUID, reason string) *AdminAuditLog {
+	id := uuid.New()
+	createdAt := time.Now().Format(time.RFC3339)
+	parsedCreatedAt, _ := time.Parse(time.RFC3339, createdAt)
+
+	return &AdminAuditLog{
+		ID:         id,
+		CreatedAt:  parsedCreatedAt,
+		AdminID:    adminId,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetId,
+		Metadata:   map[string]any{"reason": reason},
+	}
+}
+
+type AdminUserResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Name          string     `json:"name"`
+	Role          string     `json:"role"`
+	EmailVerified bool       `json:"emailVerified"`
+	DisabledAt    *time.Time `json:"disabledAt"`
+	DeletedAt     *time.Time `json:"deletedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeCatsRead     = "cats:read"
+	ScopeCatsWrite    = "cats:write"
+	ScopeMatchesRead  = "matches:read"
+	ScopeMatchesWrite = "matches:write"
+)
+
+var ApiKeyScopes = []string{
+	ScopeCatsRead,
+	ScopeCatsWrite,
+	ScopeMatchesRead,
+	ScopeMatchesWrite,
+}
+
+const ApiKeyPrefix = "cs_"
+
+type CreateApiKeyRequest struct {
+	Name   string   `json:"name" validate:"required"`
+	Scopes []string `json:"scopes" validate:"required"`
+}
+
+type ApiKey struct {
+	ID         uuid.UUID  `db:"id"`
+	UserID     uuid.UUID  `db:"user_id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	KeyHash    string     `db:"key_hash"`
+	Scopes     []string   `db:"scopes"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+type ApiKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
+
+type CreateApiKeyResponse struct {
+	ApiKeyResponse
+	Key string `json:"key"`
+}
+
+// NewApiKey returns the key together with the plain text value, which is
+// only shown to the user once.
+func NewApiKey(userId uuid.UUID, name string, scopes []string) (*ApiKey, string, error) {
+	token, _, err := NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	plain := ApiKeyPrefix + token
+
+	id := uuid.New()
+	createdAt := time.Now().Format(time.RFC3339)
+	parsedCreatedAt, _ := time.Parse(time.RFC3339, createdAt)
+
+	return &ApiKey{
+		ID:        id,
+		UserID:    userId,
+		Name:      name,
+		Prefix:    plain[:len(ApiKeyPrefix)+8],
+		KeyHash:   HashOpaqueToken(plain),
+		Scopes:    scopes,
+		CreatedAt: parsedCreatedAt,
+	}, plain, nil
+}
+
+func NewApiKeyResponse(k *ApiKey) ApiKeyResponse {
+	return ApiKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
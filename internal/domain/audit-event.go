@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditEventLoginSucceeded         = "login_succeeded"
+	AuditEventLoginFailed            = "login_failed"
+	AuditEventTokenRejected          = "token_rejected"
+	AuditEventLogout                 = "logout"
+	AuditEventSessionRevoked         = "session_revoked"
+	AuditEventPasswordResetRequested = "password_reset_requested"
+	AuditEventPasswordChanged        = "password_changed"
+	AuditEventEmailChanged           = "email_changed"
+	AuditEventAccountDeleted         = "account_deleted"
+	AuditEventTwoFactorEnabled       = "two_factor_enabled"
+	AuditEventTwoFactorDisabled      = "two_factor_disabled"
+	AuditEventApiKeyCreated          = "api_key_created"
+	AuditEventApiKeyRevoked          = "api_key_revoked"
+)
+
+// AuditEvent records something that happened to an account. ActorID is the
+// user the event belongs to, uuid.Nil when it is not known (e.g. a failed
+// login for an unregistered email).
+type AuditEvent struct {
+	ID        uuid.UUID      `db:"id"`
+	CreatedAt time.Time      `db:"created_at"`
+	ActorID   uuid.UUID      `db:"actor_id"`
+	IP        string         `db:"ip"`
+	UserAgent string         `db:"user_agent"`
+	EventType string         `db:"event_type"`
+	Metadata  map[string]any `db:"metadata"`
+}
+
+func NewAuditEvent(eventType string, actorId uuid.UUID, ip string, userAgent string, metadata map[string]any) *AuditEvent {
+	id := uuid.New()
+	createdAt := time.Now().Format(time.RFC3339)
+	parsedCreatedAt, _ := time.Parse(time.RFC3339, createdAt)
+
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	return &AuditEvent{
+		ID:        id,
+		CreatedAt: parsedCreatedAt,
+		ActorID:   actorId,
+		IP:        ip,
+		UserAgent: userAgent,
+		EventType: eventType,
+		Metadata:  metadata,
+	}
+}
+
+type AuditEventResponse struct {
+	ID        uuid.UUID      `json:"id"`
+	EventType string         `json:"eventType"`
+	IP        string         `json:"ip"`
+	UserAgent string         `json:"userAgent"`
+	Metadata  map[string]any `json:"metadata"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
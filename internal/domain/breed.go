@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	BreedNameMaxLength   = 24
+	BreedMaxAliases      = 10
+	BreedOriginMaxLength = 60
+)
+
+var BreedSizes = []string{"small", "medium", "large"}
+
+var BreedCoats = []string{"hairless", "short", "medium", "long"}
+
+// Breed is an entry of the breed catalogue. Cats name their race by Name;
+// Aliases are accepted in its place and stored as Name. Size and Coat are
+// empty when not known.
+type Breed struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	Size      string    `json:"size,omitempty"`
+	Coat      string    `json:"coat,omitempty"`
+	Origin    string    `json:"origin,omitempty"`
+}
+
+type BreedRequest struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	Size    string   `json:"size"`
+	Coat    string   `json:"coat"`
+	Origin  string   `json:"origin"`
+	Reason  string   `json:"reason"`
+}
+
+func NewBreedFromRequest(body BreedRequest) *Breed {
+	createdAt := time.Now().Format(time.RFC3339)
+	parsedCreatedAt, _ := time.Parse(time.RFC3339, createdAt)
+
+	aliases := body.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	return &Breed{
+		ID:        uuid.New(),
+		CreatedAt: parsedCreatedAt,
+		Name:      body.Name,
+		Aliases:   aliases,
+		Size:      body.Size,
+		Coat:      body.Coat,
+		Origin:    body.Origin,
+	}
+}
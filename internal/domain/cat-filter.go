@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CatListDefaultLimit = 5
+	CatListMaxLimit     = 100
+)
+
+// Fields the cat listing can be sorted by. Without a sort cats come most
+// relevant first when searching, nearest first when looking near a place
+// and newest first otherwise.
+const (
+	CatSortName       = "name"
+	CatSortAgeInMonth = "ageInMonth"
+	CatSortCreatedAt  = "createdAt"
+	// CatSortDistance needs a place to measure from
+	CatSortDistance = "distance"
+	// CatSortRelevance is only used with a search and cannot be asked for
+	CatSortRelevance = "relevance"
+)
+
+var CatSorts = []string{CatSortName, CatSortAgeInMonth, CatSortCreatedAt, CatSortDistance}
+
+// AgeComparison is one bound of an age filter, e.g. Operator ">=" and
+// Value 6.
+type AgeComparison struct {
+	Operator string
+	Value    int32
+}
+
+// CatFilter holds the validated query of the cat listing. Empty fields do
+// not filter.
+type CatFilter struct {
+	ID            *uuid.UUID
+	Races         []string
+	Sexes         []string
+	HasMatched    *bool
+	Ages          []AgeComparison
+	Owned         *bool
+	Liked         *bool
+	Search        string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Traits match all of them, or any with TraitsMatchAny
+	Traits         []string
+	TraitsMatchAny bool
+	// Near limits the cats to RadiusKm around a place
+	Near     *GeoPoint
+	RadiusKm float64
+
+	Sort     string
+	SortDesc bool
+	PageRequest
+	Offset int
+}
+
+func NewCatFilter() *CatFilter {
+	return &CatFilter{
+		PageRequest: PageRequest{Limit: CatListDefaultLimit},
+	}
+}
+
+// NewCatCursor points at cat in a listing sorted as filter says.
+func NewCatCursor(filter *CatFilter, cat *Cat) *Cursor {
+	cursor := &Cursor{Sort: filter.Sort, Desc: filter.SortDesc, ID: cat.ID}
+
+	switch filter.Sort {
+	case CatSortName:
+		cursor.Value = cat.Name
+	case CatSortAgeInMonth:
+		cursor.Value = strconv.Itoa(int(cat.AgeInMonth))
+	case CatSortDistance:
+		cursor.Value = strconv.FormatFloat(*cat.DistanceKm, 'g', -1, 64)
+	case CatSortRelevance:
+		cursor.Value = strconv.FormatFloat(cat.Search.Rank, 'g', -1, 64)
+	default:
+		cursor.Value = cat.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	return cursor
+}
+
+// CatCursorValue parses the sort value of a cursor into what the sorted
+// column holds.
+func CatCursorValue(cursor *Cursor) (any, error) {
+	switch cursor.Sort {
+	case CatSortName:
+		return cursor.Value, nil
+	case CatSortAgeInMonth:
+		age, err := strconv.ParseInt(cursor.Value, 10, 32)
+		return int32(age), err
+	case CatSortDistance, CatSortRelevance:
+		return strconv.ParseFloat(cursor.Value, 64)
+	case CatSortCreatedAt:
+		return time.Parse(time.RFC3339Nano, cursor.Value)
+	default:
+		return nil, ErrInvalidCursor
+	}
+}
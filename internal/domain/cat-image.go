@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CatImageMaxBytes     = 5 << 20
+	CatImageMaxPerCat    = 10
+	CatImageMinDimension = 200
+	CatImageMaxDimension = 6000
+)
+
+// CatImageContentTypes maps the accepted MIME types, as sniffed from the
+// file itself, to the extension stored files get.
+var CatImageContentTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// Generating the smaller variants of an image is done in the background,
+// CatImage.VariantsStatus tracks where it is at.
+const (
+	CatImageVariantsPending = "pending"
+	CatImageVariantsReady   = "ready"
+	CatImageVariantsFailed  = "failed"
+)
+
+// CatImageVariant is a resized copy of an image, stored as JSON with the
+// image.
+type CatImageVariant struct {
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+}
+
+type CatImage struct {
+	ID          uuid.UUID `db:"id"`
+	CatID       uuid.UUID `db:"cat_id"`
+	StorageKey  string    `db:"storage_key"`
+	URL         string    `db:"url"`
+	ContentType string    `db:"content_type"`
+	Width       int       `db:"width"`
+	Height      int       `db:"height"`
+	SizeBytes   int       `db:"size_bytes"`
+	Position    int       `db:"position"`
+	CreatedAt   time.Time `db:"created_at"`
+
+	Variants         map[string]CatImageVariant `db:"variants"`
+	VariantsStatus   string                     `db:"variants_status"`
+	VariantsAttempts int                        `db:"variants_attempts"`
+}
+
+func NewCatImage(catId uuid.UUID, contentType string) *CatImage {
+	id := uuid.New()
+	createdAt := time.Now().Format(time.RFC3339)
+	parsedCreatedAt, _ := time.Parse(time.RFC3339, createdAt)
+
+	return &CatImage{
+		ID:          id,
+		CatID:       catId,
+		StorageKey:  "cats/" + catId.String() + "/" + id.String() + "." + CatImageContentTypes[contentType],
+		ContentType: contentType,
+		CreatedAt:   parsedCreatedAt,
+
+		Variants:       map[string]CatImageVariant{},
+		VariantsStatus: CatImageVariantsPending,
+	}
+}
+
+type ReorderCatImagesRequest struct {
+	ImageIds []uuid.UUID `json:"imageIds"`
+}
+
+type CatImageResponse struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"contentType"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	SizeBytes   int       `json:"sizeBytes"`
+	Position    int       `json:"position"`
+	CreatedAt   time.Time `json:"createdAt"`
+
+	Variants       map[string]CatImageVariantResponse `json:"variants"`
+	VariantsStatus string                             `json:"variantsStatus"`
+}
+
+func NewCatImageResponse(image *CatImage) CatImageResponse {
+	return CatImageResponse{
+		ID:          image.ID,
+		URL:         image.URL,
+		ContentType: image.ContentType,
+		Width:       image.Width,
+		Height:      image.Height,
+		SizeBytes:   image.SizeBytes,
+		Position:    image.Position,
+		CreatedAt:   image.CreatedAt,
+
+		Variants:       NewCatImageVariantResponses(image.Variants),
+		VariantsStatus: image.VariantsStatus,
+	}
+}
+
+type CatImageVariantResponse struct {
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+}
+
+// NewCatImageVariantResponses maps variant names to their responses. The
+// map is empty, never nil, until the variants are generated.
+func NewCatImageVariantResponses(variants map[string]CatImageVariant) map[string]CatImageVariantResponse {
+	res := make(map[string]CatImageVariantResponse, len(variants))
+	for name, variant := range variants {
+		res[name] = CatImageVariantResponse{
+			URL:         variant.URL,
+			ContentType: variant.ContentType,
+			Width:       variant.Width,
+			Height:      variant.Height,
+		}
+	}
+
+	return res
+}
+
+// CatImageSummary is an image as listed with its cat.
+type CatImageSummary struct {
+	ID       uuid.UUID                          `json:"id"`
+	URL      string                             `json:"url"`
+	Variants map[string]CatImageVariantResponse `json:"variants"`
+}
+
+func NewCatImageSummary(image *CatImage) CatImageSummary {
+	return CatImageSummary{
+		ID:       image.ID,
+		URL:      image.URL,
+		Variants: NewCatImageVariantResponses(image.Variants),
+	}
+}
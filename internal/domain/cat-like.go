@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CatLikeListDefaultLimit = 20
+	CatLikeListMaxLimit     = 100
+	// CatLikeSortLikedAt is the sort of both like listings, newest first
+	CatLikeSortLikedAt = "likedAt"
+)
+
+// LikedCat is a cat the user bookmarked.
+type LikedCat struct {
+	Cat
+	LikedAt time.Time `json:"likedAt"`
+}
+
+// CatLiker is someone who liked a cat, as its owner sees them.
+type CatLiker struct {
+	UserID  uuid.UUID `json:"-"`
+	Name    string    `json:"name"`
+	LikedAt time.Time `json:"likedAt"`
+}
+
+type CatLikeStatusResponse struct {
+	Liked     bool `json:"liked"`
+	LikeCount int  `json:"likeCount"`
+}
+
+func NewLikedCatCursor(cat *LikedCat) *Cursor {
+	return &Cursor{
+		Sort:  CatLikeSortLikedAt,
+		Desc:  true,
+		Value: cat.LikedAt.Format(time.RFC3339Nano),
+		ID:    cat.ID,
+	}
+}
+
+func NewCatLikerCursor(liker *CatLiker) *Cursor {
+	return &Cursor{
+		Sort:  CatLikeSortLikedAt,
+		Desc:  true,
+		Value: liker.LikedAt.Format(time.RFC3339Nano),
+		ID:    liker.UserID,
+	}
+}
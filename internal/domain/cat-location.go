@@ -0,0 +1,114 @@
+package domain
+
+import "math"
+
+// Locations are stored rounded to two decimals, about a kilometre, so the
+// exact place a cat lives is never kept.
+const coordinateScale = 100
+
+const (
+	EarthRadiusKm       = 6371.0
+	NearDefaultRadiusKm = 25
+	NearMaxRadiusKm     = 500
+)
+
+// CatLocation is the coarse location of a cat. Other owners only ever see
+// the city.
+type CatLocation struct {
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	City      string   `json:"city,omitempty"`
+}
+
+// Round coarsens the coordinates before they are stored.
+func (l *CatLocation) Round() {
+	if l.Latitude != nil {
+		latitude := math.Round(*l.Latitude*coordinateScale) / coordinateScale
+		l.Latitude = &latitude
+	}
+	if l.Longitude != nil {
+		longitude := math.Round(*l.Longitude*coordinateScale) / coordinateScale
+		l.Longitude = &longitude
+	}
+}
+
+// Point is where the location is, nil without coordinates.
+func (l *CatLocation) Point() *GeoPoint {
+	if l == nil || l.Latitude == nil || l.Longitude == nil {
+		return nil
+	}
+
+	return &GeoPoint{Latitude: *l.Latitude, Longitude: *l.Longitude}
+}
+
+// Public is what anyone but the owner may see of the location, nil when
+// that is nothing.
+func (l *CatLocation) Public() *CatLocation {
+	if l == nil || l.City == "" {
+		return nil
+	}
+
+	return &CatLocation{City: l.City}
+}
+
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// DistanceKm is the great circle distance to q by the haversine formula.
+func (p GeoPoint) DistanceKm(q GeoPoint) float64 {
+	lat1, lat2 := p.Latitude*math.Pi/180, q.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (q.Longitude - p.Longitude) * math.Pi / 180
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * EarthRadiusKm * math.Asin(math.Sqrt(min(h, 1)))
+}
+
+// GeoBox bounds every point within some distance of a centre. When it
+// crosses the antimeridian MinLongitude is greater than MaxLongitude, near
+// the poles AllLongitudes is set instead.
+type GeoBox struct {
+	MinLatitude   float64
+	MaxLatitude   float64
+	MinLongitude  float64
+	MaxLongitude  float64
+	AllLongitudes bool
+}
+
+// BoundingBox is a cheap prefilter for points within radiusKm of p, the
+// exact distance still has to be checked.
+func (p GeoPoint) BoundingBox(radiusKm float64) GeoBox {
+	deltaLatitude := radiusKm / EarthRadiusKm * 180 / math.Pi
+
+	box := GeoBox{
+		MinLatitude:  max(p.Latitude-deltaLatitude, -90),
+		MaxLatitude:  min(p.Latitude+deltaLatitude, 90),
+		MinLongitude: -180,
+		MaxLongitude: 180,
+	}
+	if box.MinLatitude == -90 || box.MaxLatitude == 90 {
+		box.AllLongitudes = true
+		return box
+	}
+
+	// widest at the edge of the box nearest to a pole
+	widest := max(math.Abs(box.MinLatitude), math.Abs(box.MaxLatitude))
+	deltaLongitude := deltaLatitude / math.Cos(widest*math.Pi/180)
+	if deltaLongitude >= 180 {
+		box.AllLongitudes = true
+		return box
+	}
+
+	box.MinLongitude = p.Longitude - deltaLongitude
+	box.MaxLongitude = p.Longitude + deltaLongitude
+	if box.MinLongitude < -180 {
+		box.MinLongitude += 360
+	}
+	if box.MaxLongitude > 180 {
+		box.MaxLongitude -= 360
+	}
+
+	return box
+}
@@ -12,6 +12,11 @@ var (
 	CatMatchStatusRejected = "rejected"
 )
 
+const (
+	CatMatchListDefaultLimit = 20
+	CatMatchListMaxLimit     = 100
+)
+
 var CatMatchStatuses = []string{
 	CatMatchStatusPending,
 	CatMatchStatusAccepted,
@@ -73,3 +78,14 @@ func NewCatMatch() *CatMatch {
 		CreatedAt: parsedCreatedAt,
 	}
 }
+
+// NewCatMatchCursor points at match in the match listing, which is always
+// newest first.
+func NewCatMatchCursor(match *CatMatch) *Cursor {
+	return &Cursor{
+		Sort:  CatSortCreatedAt,
+		Desc:  true,
+		Value: match.CreatedAt.Format(time.RFC3339Nano),
+		ID:    match.ID,
+	}
+}
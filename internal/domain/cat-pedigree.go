@@ -0,0 +1,73 @@
+package domain
+
+import "github.com/google/uuid"
+
+const (
+	CatPedigreeDefaultGenerations = 3
+	CatPedigreeMaxGenerations     = 8
+)
+
+// PedigreeCat is a cat in a pedigree with its parents, as far back as asked
+// for. A parent is nil when it is not known or beyond the generations asked
+// for.
+type PedigreeCat struct {
+	ID         uuid.UUID    `json:"id"`
+	Name       string       `json:"name"`
+	Race       string       `json:"race"`
+	Sex        string       `json:"sex"`
+	AgeInMonth int32        `json:"ageInMonth"`
+	Sire       *PedigreeCat `json:"sire"`
+	Dam        *PedigreeCat `json:"dam"`
+}
+
+// Ancestor is a cat of a lineage with the links to its parents.
+type Ancestor struct {
+	ID         uuid.UUID
+	Name       string
+	Race       string
+	Sex        string
+	AgeInMonth int32
+	SireID     *uuid.UUID
+	DamID      *uuid.UUID
+}
+
+// CommonAncestor is a cat in the lineage of two cats, the nearest
+// generation back from each.
+type CommonAncestor struct {
+	ID              uuid.UUID
+	Name            string
+	Generation      int
+	OtherGeneration int
+}
+
+// NewPedigree arranges the ancestors of the cat catId into its pedigree,
+// generations deep. ancestors must hold the cat itself.
+func NewPedigree(catId uuid.UUID, ancestors []Ancestor, generations int) *PedigreeCat {
+	byId := make(map[uuid.UUID]*Ancestor, len(ancestors))
+	for i := range ancestors {
+		byId[ancestors[i].ID] = &ancestors[i]
+	}
+
+	var build func(id *uuid.UUID, generation int) *PedigreeCat
+	build = func(id *uuid.UUID, generation int) *PedigreeCat {
+		if id == nil || generation > generations {
+			return nil
+		}
+		ancestor, ok := byId[*id]
+		if !ok {
+			return nil
+		}
+
+		return &PedigreeCat{
+			ID:         ancestor.ID,
+			Name:       ancestor.Name,
+			Race:       ancestor.Race,
+			Sex:        ancestor.Sex,
+			AgeInMonth: ancestor.AgeInMonth,
+			Sire:       build(ancestor.SireID, generation+1),
+			Dam:        build(ancestor.DamID, generation+1),
+		}
+	}
+
+	return build(&catId, 0)
+}
@@ -0,0 +1,26 @@
+package domain
+
+const (
+	CatRecommendationDefaultLimit = 10
+	CatRecommendationMaxLimit     = 50
+	// CatRecommendationPoolSize caps how many eligible cats are scored, the
+	// nearest and then newest are taken
+	CatRecommendationPoolSize = 500
+)
+
+// RecommendationPreferences steer the scoring of recommendations.
+type RecommendationPreferences struct {
+	// Races are the preferred breeds, the breed of the cat itself when empty
+	Races []string
+	Limit int
+}
+
+// CatRecommendation is a cat proposed as a partner, with a score from 0 to
+// 100 and the reasons behind it, strongest first. Warnings are what a match
+// request would warn about.
+type CatRecommendation struct {
+	Cat
+	Score    float64  `json:"score"`
+	Reasons  []string `json:"reasons"`
+	Warnings []string `json:"warnings,omitempty"`
+}
@@ -0,0 +1,26 @@
+package domain
+
+// CatMaxTraits caps the traits of one cat.
+const CatMaxTraits = 20
+
+// Trait matching of the cat listing, every trait asked for by default.
+const (
+	CatTraitMatchAll = "all"
+	CatTraitMatchAny = "any"
+)
+
+// TraitCategory groups the traits cats are described by, e.g. temperament
+// or coat. Unless Multiple a cat has at most one trait of the category.
+// The vocabulary lives in the traits tables.
+type TraitCategory struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Multiple bool    `json:"multiple"`
+	Traits   []Trait `json:"traits"`
+}
+
+type Trait struct {
+	ID         string `json:"id"`
+	CategoryID string `json:"-"`
+	Name       string `json:"name"`
+}
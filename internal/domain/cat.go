@@ -17,17 +17,37 @@ type UpdateCatResponse struct {
 }
 
 type Cat struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
-	Name        string    `json:"name" db:"name"`
-	Race        string    `json:"race" db:"race"`
-	Sex         string    `json:"sex" db:"sex"`
-	AgeInMonth  int32     `json:"ageInMonth" db:"age_in_month"`
-	Description string    `json:"description" db:"description"`
-	ImageUrls   []string  `json:"imageUrls" db:"image_urls"`
-	HasMatched  bool      `json:"hasMatched" db:"has_matched"`
-	OwnedById   uuid.UUID `json:"-" db:"owned_by_id"`
-	OwnedBy     User      `json:"-"`
+	ID          uuid.UUID         `json:"id" db:"id"`
+	CreatedAt   time.Time         `json:"createdAt" db:"created_at"`
+	Name        string            `json:"name" db:"name"`
+	Race        string            `json:"race" db:"race"`
+	Sex         string            `json:"sex" db:"sex"`
+	AgeInMonth  int32             `json:"ageInMonth" db:"age_in_month"`
+	Description string            `json:"description" db:"description"`
+	ImageUrls   []string          `json:"imageUrls" db:"image_urls"`
+	Images      []CatImageSummary `json:"images" db:"-"`
+	Traits      []string          `json:"traits" db:"-"`
+	HasMatched  bool              `json:"hasMatched" db:"has_matched"`
+	LikeCount   int               `json:"likeCount" db:"-"`
+	Liked       bool              `json:"liked" db:"-"`
+	OwnedById   uuid.UUID         `json:"-" db:"owned_by_id"`
+	OwnedBy     User              `json:"-"`
+	Location    *CatLocation      `json:"location,omitempty" db:"-"`
+	// SireID and DamID are the father and mother, when known
+	SireID *uuid.UUID `json:"sireId,omitempty" db:"sire_id"`
+	DamID  *uuid.UUID `json:"damId,omitempty" db:"dam_id"`
+	// DistanceKm is only set when looking for cats near a place
+	DistanceKm *float64 `json:"distanceKm,omitempty" db:"-"`
+	// Search is only set when the cats were searched for
+	Search *CatSearchMatch `json:"search,omitempty" db:"-"`
+}
+
+// CatSearchMatch tells how well a cat matched a search. The highlights are
+// HTML escaped, with the matched words wrapped in <mark> tags.
+type CatSearchMatch struct {
+	Rank                 float64 `json:"rank"`
+	NameHighlight        string  `json:"nameHighlight"`
+	DescriptionHighlight string  `json:"descriptionHighlight"`
 }
 
 type CreateCatResponse struct {
@@ -36,15 +56,110 @@ type CreateCatResponse struct {
 }
 
 type CatResponse struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	Race        string    `json:"race"`
-	Sex         string    `json:"sex"`
-	AgeInMonth  int32     `json:"ageInMonth"`
-	Description string    `json:"description"`
-	ImageUrls   []string  `json:"imageUrls"`
-	HasMatched  bool      `json:"hasMatched"`
-	CreatedAt   time.Time `json:"createdAt"`
+	ID          uuid.UUID         `json:"id"`
+	Name        string            `json:"name"`
+	Race        string            `json:"race"`
+	Sex         string            `json:"sex"`
+	AgeInMonth  int32             `json:"ageInMonth"`
+	Description string            `json:"description"`
+	ImageUrls   []string          `json:"imageUrls"`
+	Images      []CatImageSummary `json:"images"`
+	Traits      []string          `json:"traits"`
+	HasMatched  bool              `json:"hasMatched"`
+	LikeCount   int               `json:"likeCount"`
+	Liked       bool              `json:"liked"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	Location    *CatLocation      `json:"location,omitempty"`
+}
+
+// Match status of a cat as shown on its detail.
+const (
+	CatMatchStateNone    = "none"
+	CatMatchStatePending = "pending"
+	CatMatchStateMatched = "matched"
+)
+
+// CatDetail is a cat with what its detail page shows about its owner and
+// match requests.
+type CatDetail struct {
+	Cat
+	OwnerName        string
+	SentRequests     int
+	ReceivedRequests int
+	// the approved match of the cat, if any
+	MatchId      uuid.NullUUID
+	MatchedCatId uuid.NullUUID
+}
+
+type CatOwnerResponse struct {
+	Name string `json:"name"`
+}
+
+type CatMatchStateResponse struct {
+	Status       string     `json:"status"`
+	MatchId      *uuid.UUID `json:"matchId,omitempty"`
+	MatchedCatId *uuid.UUID `json:"matchedCatId,omitempty"`
+}
+
+type CatPendingRequestsResponse struct {
+	Sent     int `json:"sent"`
+	Received int `json:"received"`
+}
+
+type CatDetailResponse struct {
+	CatResponse
+	SireID          *uuid.UUID                 `json:"sireId"`
+	DamID           *uuid.UUID                 `json:"damId"`
+	Owner           CatOwnerResponse           `json:"owner"`
+	IsOwner         bool                       `json:"isOwner"`
+	Match           CatMatchStateResponse      `json:"match"`
+	PendingRequests CatPendingRequestsResponse `json:"pendingRequests"`
+}
+
+func NewCatDetailResponse(detail *CatDetail, userId uuid.UUID) CatDetailResponse {
+	match := CatMatchStateResponse{Status: CatMatchStateNone}
+	switch {
+	case detail.MatchId.Valid:
+		match.Status = CatMatchStateMatched
+		match.MatchId = &detail.MatchId.UUID
+		match.MatchedCatId = &detail.MatchedCatId.UUID
+	case detail.SentRequests+detail.ReceivedRequests > 0:
+		match.Status = CatMatchStatePending
+	}
+
+	isOwner := detail.OwnedById == userId
+	location := detail.Location
+	if !isOwner {
+		location = location.Public()
+	}
+
+	return CatDetailResponse{
+		CatResponse: CatResponse{
+			ID:          detail.ID,
+			Name:        detail.Name,
+			Race:        detail.Race,
+			Sex:         detail.Sex,
+			AgeInMonth:  detail.AgeInMonth,
+			Description: detail.Description,
+			ImageUrls:   detail.ImageUrls,
+			Images:      detail.Images,
+			Traits:      detail.Traits,
+			HasMatched:  detail.HasMatched,
+			LikeCount:   detail.LikeCount,
+			Liked:       detail.Liked,
+			CreatedAt:   detail.CreatedAt,
+			Location:    location,
+		},
+		SireID:  detail.SireID,
+		DamID:   detail.DamID,
+		Owner:   CatOwnerResponse{Name: detail.OwnerName},
+		IsOwner: isOwner,
+		Match:   match,
+		PendingRequests: CatPendingRequestsResponse{
+			Sent:     detail.SentRequests,
+			Received: detail.ReceivedRequests,
+		},
+	}
 }
 
 func NewCat() *Cat {
@@ -59,19 +174,6 @@ func NewCat() *Cat {
 	}
 }
 
-var CatRace = []string{
-	"Persian",
-	"Maine Coon",
-	"Siamese",
-	"Ragdoll",
-	"Bengal",
-	"Sphynx",
-	"British Shorthair",
-	"Abyssinian",
-	"Scottish Fold",
-	"Birman",
-}
-
 var CatSex = []string{"male", "female"}
 
 var CatQueryParams = []string{
@@ -83,5 +185,16 @@ var CatQueryParams = []string{
 	"hasMatched",
 	"ageInMonth",
 	"owned",
+	"liked",
+	"trait",
+	"traitMatch",
 	"search",
+	"createdAfter",
+	"createdBefore",
+	"sort",
+	"order",
+	"near",
+	"radiusKm",
+	"cursor",
+	"includeTotal",
 }
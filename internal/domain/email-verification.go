@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const EmailVerificationTokenTTL = time.Hour * 24
+
+type EmailVerificationToken struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    uuid.UUID  `db:"user_id"`
+	TokenHash string     `db:"token_hash"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+func NewEmailVerificationToken(userId uuid.UUID) *EmailVerificationToken {
+	id := uuid.New()
+	createdAt := time.Now().Format(time.RFC3339)
+	parsedCreatedAt, _ := time.Parse(time.RFC3339, createdAt)
+
+	return &EmailVerificationToken{
+		ID:        id,
+		UserID:    userId,
+		CreatedAt: parsedCreatedAt,
+		ExpiresAt: parsedCreatedAt.Add(EmailVerificationTokenTTL),
+	}
+}
@@ -45,6 +45,14 @@ func NewUnauthenticatedError(message string) MessageErr {
 	}
 }
 
+func NewEmailNotVerifiedError(message string) MessageErr {
+	return &ErrorData{
+		ErrMessage: message,
+		ErrStatus:  http.StatusForbidden,
+		ErrError:   "EMAIL_NOT_VERIFIED",
+	}
+}
+
 func NewNotFoundError(message string) MessageErr {
 	return &ErrorData{
 		ErrMessage: message,
@@ -85,6 +93,14 @@ func NewUnprocessibleEntityError(message string) MessageErr {
 	}
 }
 
+func NewTooManyRequestsError(message string) MessageErr {
+	return &ErrorData{
+		ErrMessage: message,
+		ErrStatus:  http.StatusTooManyRequests,
+		ErrError:   "TOO_MANY_REQUESTS",
+	}
+}
+
 func NewConflictError(message string) MessageErr {
 	return &ErrorData{
 		ErrMessage: message,
@@ -93,6 +109,29 @@ func NewConflictError(message string) MessageErr {
 	}
 }
 
+type RuleViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// RuleViolationsData is an ErrorData that also lists every rule the request
+// broke.
+type RuleViolationsData struct {
+	ErrorData
+	Violations []RuleViolation `json:"violations"`
+}
+
+func NewPasswordPolicyError(violations []RuleViolation) MessageErr {
+	return &RuleViolationsData{
+		ErrorData: ErrorData{
+			ErrMessage: "password does not meet the password policy",
+			ErrStatus:  http.StatusBadRequest,
+			ErrError:   "PASSWORD_POLICY_VIOLATION",
+		},
+		Violations: violations,
+	}
+}
+
 func CheckErr(err error) {
 	if err != nil {
 		log.Fatalln("Error:", err.Error())
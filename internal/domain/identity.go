@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const OIDCLoginStateTTL = time.Minute * 10
+
+// OIDCLoginState is what the callback needs to finish a login started by
+// /v1/user/oidc/start. It is stored under the hash of the state parameter.
+type OIDCLoginState struct {
+	StateHash    string    `db:"state_hash"`
+	CodeVerifier string    `db:"code_verifier"`
+	Nonce        string    `db:"nonce"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+func NewOIDCLoginState(state string, codeVerifier string, nonce string) *OIDCLoginState {
+	createdAt := time.Now().Format(time.RFC3339)
+	parsedCreatedAt, _ := time.Parse(time.RFC3339, createdAt)
+
+	return &OIDCLoginState{
+		StateHash:    HashOpaqueToken(state),
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		CreatedAt:    parsedCreatedAt,
+		ExpiresAt:    parsedCreatedAt.Add(OIDCLoginStateTTL),
+	}
+}
+
+// UserIdentity links an account of an external identity provider to a user.
+type UserIdentity struct {
+	ID        uuid.UUID `db:"id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Issuer    string    `db:"issuer"`
+	Subject   string    `db:"subject"`
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func NewUserIdentity(userId uuid.UUID, issuer string, subject string, email string) *UserIdentity {
+	id := uuid.New()
+	createdAt := time.Now().Format(time.RFC3339)
+	parsedCreatedAt, _ := time.Parse(time.RFC3339, createdAt)
+
+	return &UserIdentity{
+		ID:        id,
+		UserID:    userId,
+		Issuer:    issuer,
+		Subject:   subject,
+		Email:     email,
+		CreatedAt: parsedCreatedAt,
+	}
+}
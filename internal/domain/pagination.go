@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor marks a row of a listing by its sort value and id, the page it
+// asks for starts right after (or, Backward, right before) that row. It
+// travels to clients as an opaque token.
+type Cursor struct {
+	Sort     string    `json:"s"`
+	Desc     bool      `json:"d"`
+	Value    string    `json:"v"`
+	ID       uuid.UUID `json:"i"`
+	Backward bool      `json:"b,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func EncodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := Cursor{}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// Time reads the value of a cursor into a listing sorted by time.
+func (c *Cursor) Time() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+
+	return t, nil
+}
+
+// PageRequest is the paging part of a listing query.
+type PageRequest struct {
+	Limit        int
+	Cursor       *Cursor
+	IncludeTotal bool
+}
+
+type Pagination struct {
+	NextCursor *string `json:"nextCursor"`
+	PrevCursor *string `json:"prevCursor"`
+	// Total counts every row matching the filters, only when asked for
+	Total *int `json:"total,omitempty"`
+}
+
+// NewPagination makes the cursors around a page. first and last are the
+// cursors of its first and last row, nil when it is empty. hasMore tells
+// whether rows were left over in the direction the page was read, skipped
+// whether a forward read started past the first row.
+func NewPagination(page PageRequest, first *Cursor, last *Cursor, hasMore bool, skipped bool) Pagination {
+	pagination := Pagination{}
+	if first == nil || last == nil {
+		return pagination
+	}
+
+	hasNext, hasPrev := hasMore, skipped
+	if page.Cursor != nil && page.Cursor.Backward {
+		// reading backward we came from the next page
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if hasNext {
+		next := *last
+		next.Backward = false
+		token := EncodeCursor(next)
+		pagination.NextCursor = &token
+	}
+	if hasPrev {
+		prev := *first
+		prev.Backward = true
+		token := EncodeCursor(prev)
+		pagination.PrevCursor = &token
+	}
+
+	return pagination
+}
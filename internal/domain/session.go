@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const SessionTTL = time.Hour * 24 * 30
+
+// UserAgentMaxBytes bounds the User-Agent kept with a session, clients
+// choose the header freely.
+const UserAgentMaxBytes = 512
+
+// SessionLastSeenInterval is how stale last_seen_at may get before a request
+// updates it; it only needs to be roughly right.
+const SessionLastSeenInterval = time.Minute
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type Session struct {
+	ID               uuid.UUID  `db:"id"`
+	UserID           uuid.UUID  `db:"user_id"`
+	RefreshTokenHash string     `db:"refresh_token_hash"`
+	CreatedAt        time.Time  `db:"created_at"`
+	ExpiresAt        time.Time  `db:"expires_at"`
+	RevokedAt        *time.Time `db:"revoked_at"`
+	UserAgent        string     `db:"user_agent"`
+	DeviceName       string     `db:"device_name"`
+	IP               string     `db:"ip"`
+	LastSeenAt       time.Time  `db:"last_seen_at"`
+}
+
+func NewSession(userId uuid.UUID, userAgent string, ip string) *Session {
+	id := uuid.New()
+	createdAt := time.Now().Format(time.RFC3339)
+	parsedCreatedAt, _ := time.Parse(time.RFC3339, createdAt)
+
+	return &Session{
+		ID:         id,
+		UserID:     userId,
+		CreatedAt:  parsedCreatedAt,
+		ExpiresAt:  parsedCreatedAt.Add(SessionTTL),
+		UserAgent:  userAgent,
+		DeviceName: DeviceName(userAgent),
+		IP:         ip,
+		LastSeenAt: parsedCreatedAt,
+	}
+}
+
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	DeviceName string    `json:"deviceName"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"`
+}
+
+func NewSessionResponse(s *Session, currentId uuid.UUID) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		DeviceName: s.DeviceName,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		Current:    s.ID == currentId,
+	}
+}
+
+var (
+	// order matters: Edge and Opera also claim to be Chrome, Chrome also
+	// claims to be Safari
+	browserNames = [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+		{"okhttp/", "OkHttp"},
+		{"Go-http-client/", "Go HTTP client"},
+	}
+	// Android before Linux, iPhone and iPad before Mac OS X
+	osNames = [][2]string{
+		{"Windows", "Windows"},
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// TruncateUserAgent cuts userAgent to UserAgentMaxBytes and drops invalid
+// UTF-8, which the database would refuse.
+func TruncateUserAgent(userAgent string) string {
+	if len(userAgent) > UserAgentMaxBytes {
+		userAgent = userAgent[:UserAgentMaxBytes]
+	}
+
+	return strings.ToValidUTF8(userAgent, "")
+}
+
+// DeviceName turns a User-Agent into a short label like "Firefox on Linux".
+func DeviceName(userAgent string) string {
+	browser := matchName(userAgent, browserNames)
+	os := matchName(userAgent, osNames)
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os + " device"
+	default:
+		return "Unknown device"
+	}
+}
+
+func matchName(userAgent string, names [][2]string) string {
+	for _, name := range names {
+		if strings.Contains(userAgent, name[0]) {
+			return name[1]
+		}
+	}
+
+	return ""
+}
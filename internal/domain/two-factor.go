@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+const (
+	TwoFactorChallengeTTL = time.Minute * 5
+	TwoFactorIssuer       = "Cats Social"
+	RecoveryCodeCount     = 10
+
+	twoFactorChallengePurpose = "2fa"
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+}
+
+type TwoFactor struct {
+	Secret       *string    `db:"totp_secret"`
+	EnabledAt    *time.Time `db:"totp_enabled_at"`
+	LastUsedStep int64      `db:"totp_last_used_step"`
+}
+
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// NewRecoveryCodes returns one-time recovery codes formatted as xxxxx-xxxxx
+// together with the hashes to persist.
+func NewRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([]string, 0, RecoveryCodeCount)
+
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		for j := range b {
+			b[j] = recoveryCodeAlphabet[int(b[j])%len(recoveryCodeAlphabet)]
+		}
+
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashOpaqueToken(normalized)
+}
+
+// NewTwoFactorChallenge issues the short-lived token a client exchanges,
+// together with a second factor, for a session. It carries no email or
+// session id, so it is never accepted as an access token.
+func NewTwoFactorChallenge(userId uuid.UUID) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":     userId.String(),
+		"purpose": twoFactorChallengePurpose,
+		"exp":     time.Now().Add(TwoFactorChallengeTTL).Unix(),
+	}
+
+	return NewTokenService().GetKeyring().Sign(claims)
+}
+
+func ParseTwoFactorChallenge(tokenString string) (uuid.UUID, MessageErr) {
+	invalidChallengeErr := NewUnauthenticatedError("invalid or expired challenge token")
+
+	token, err := jwt.Parse(tokenString, NewTokenService().GetKeyring().Keyfunc)
+	if err != nil || !token.Valid {
+		return uuid.Nil, invalidChallengeErr
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != twoFactorChallengePurpose {
+		return uuid.Nil, invalidChallengeErr
+	}
+
+	sub, _ := claims["sub"].(string)
+	userId, err := uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, invalidChallengeErr
+	}
+
+	return userId, nil
+}
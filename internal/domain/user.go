@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"cats-social/internal/keyring"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -12,24 +14,24 @@ import (
 )
 
 type TokenService interface {
-	GetJWTSecret() string
+	GetKeyring() *keyring.Keyring
 	GetBcryptSalt() string
 }
 
 type tokenService struct {
-	JWTSecret  string
+	Keyring    *keyring.Keyring
 	BcryptSalt string
 }
 
 func NewTokenService() TokenService {
 	return &tokenService{
-		JWTSecret:  os.Getenv("JWT_SECRET"),
+		Keyring:    keyring.Default(),
 		BcryptSalt: os.Getenv("BCRYPT_SALT"),
 	}
 }
 
-func (t *tokenService) GetJWTSecret() string {
-	return t.JWTSecret
+func (t *tokenService) GetKeyring() *keyring.Keyring {
+	return t.Keyring
 }
 
 func (t *tokenService) GetBcryptSalt() string {
@@ -39,7 +41,7 @@ func (t *tokenService) GetBcryptSalt() string {
 type NewUserRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Name     string `json:"name" validate:"required,min=5,max=50"`
-	Password string `json:"password" validate:"required,min=5,max=15"`
+	Password string `json:"password" validate:"required"`
 }
 
 type LoginRequest struct {
@@ -55,13 +57,26 @@ type UserResponse struct {
 }
 
 type User struct {
-	Id           uuid.UUID    `json:"id" db:"id"`
-	Email        string       `json:"email" db:"email" validate:"required,email"`
-	Name         string       `json:"name" db:"name" validate:"required,min=5,max=50"`
-	Password     string       `json:"password" db:"password" validate:"required,min=5,max=15"`
-	TokenService TokenService `json:"accessToken"`
-	CreatedAt    time.Time    `json:"createdAt" db:"created_at"`
-}
+	Id              uuid.UUID    `json:"id" db:"id"`
+	Email           string       `json:"email" db:"email" validate:"required,email"`
+	Name            string       `json:"name" db:"name" validate:"required,min=5,max=50"`
+	Password        string       `json:"password" db:"password" validate:"required"`
+	TokenService    TokenService `json:"accessToken"`
+	CreatedAt       time.Time    `json:"createdAt" db:"created_at"`
+	SessionId       uuid.UUID    `json:"-"`
+	EmailVerifiedAt *time.Time   `json:"-" db:"email_verified_at"`
+	Role            string       `json:"-" db:"role"`
+	DisabledAt      *time.Time   `json:"-" db:"disabled_at"`
+	TOTPEnabledAt   *time.Time   `json:"-" db:"totp_enabled_at"`
+	// ApiKeyScopes is set when the request was authenticated with an API key
+	// instead of a session token; it limits what the request may do.
+	ApiKeyScopes []string `json:"-"`
+}
+
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
 
 func NewUser() *User {
 	id := uuid.New()
@@ -70,9 +85,32 @@ func NewUser() *User {
 	return &User{
 		Id:           id,
 		TokenService: token,
+		Role:         UserRoleUser,
 	}
 }
 
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u *User) IsApiKeyAuthenticated() bool {
+	return u.ApiKeyScopes != nil
+}
+
+// HasScope reports whether the request may act within scope. Session tokens
+// carry every scope.
+func (u *User) HasScope(scope string) bool {
+	return !u.IsApiKeyAuthenticated() || slices.Contains(u.ApiKeyScopes, scope)
+}
+
+func (u *User) IsTwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
 var invalidTokenErr = NewUnauthenticatedError("invalid token")
 
 func (u *User) HashPassword() MessageErr {
@@ -100,23 +138,16 @@ func (u *User) GenerateToken() (string, error) {
 	claims := jwt.MapClaims{
 		"id":    u.Id.String(),
 		"email": u.Email,
+		"sid":   u.SessionId.String(),
+		"role":  u.Role,
 		"exp":   time.Now().Add(time.Hour * 8).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, _ := token.SignedString([]byte(u.TokenService.GetJWTSecret()))
-
-	return tokenString, nil
+	return u.TokenService.GetKeyring().Sign(claims)
 }
 
 func (u *User) parseToken(tokenString string) (*jwt.Token, MessageErr) {
-	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, invalidTokenErr
-		}
-
-		return []byte(u.TokenService.GetJWTSecret()), nil
-	})
+	token, err := jwt.Parse(tokenString, u.TokenService.GetKeyring().Keyfunc)
 	if err != nil {
 		return nil, invalidTokenErr
 	}
@@ -143,6 +174,23 @@ func (u *User) bindTokenToUserEntity(claim jwt.MapClaims) MessageErr {
 
 	u.Email = email
 
+	sidString, ok := claim["sid"].(string)
+	if !ok {
+		return invalidTokenErr
+	}
+
+	sid, err := uuid.Parse(sidString)
+	if err != nil {
+		return invalidTokenErr
+	}
+	u.SessionId = sid
+
+	role, ok := claim["role"].(string)
+	if !ok {
+		return invalidTokenErr
+	}
+	u.Role = role
+
 	return nil
 }
 
@@ -180,3 +228,28 @@ func (u *User) ValidateToken(bearerToken string) error {
 
 	return nil
 }
+
+type UpdateProfileRequest struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword string  `json:"currentPassword"`
+}
+
+type ProfileResponse struct {
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	EmailVerified bool      `json:"emailVerified"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+func NewProfileResponse(u *User) ProfileResponse {
+	return ProfileResponse{
+		ID:            u.Id,
+		Email:         u.Email,
+		Name:          u.Name,
+		EmailVerified: u.IsEmailVerified(),
+		CreatedAt:     u.CreatedAt,
+	}
+}
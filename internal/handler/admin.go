@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"cats-social/internal/domain"
+	"cats-social/internal/service"
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminHandler interface {
+	GetAllUsers() gin.HandlerFunc
+	DisableUser() gin.HandlerFunc
+	EnableUser() gin.HandlerFunc
+	HardDeleteCat() gin.HandlerFunc
+	CancelCatMatch() gin.HandlerFunc
+}
+
+type adminHandler struct {
+	adminService service.AdminService
+}
+
+func NewAdminHandler(adminService service.AdminService) AdminHandler {
+	return &adminHandler{
+		adminService: adminService,
+	}
+}
+
+func (a *adminHandler) GetAllUsers() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+		if err != nil || limit < 1 || limit > 100 {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("limit should be between 1 and 100"))
+			return
+		}
+
+		offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+		if err != nil || offset < 0 {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("offset should be a positive number"))
+			return
+		}
+
+		users, msgErr := a.adminService.GetAllUsers(ctx, limit, offset)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success", "data": users})
+	}
+}
+
+func (a *adminHandler) DisableUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		a.userAction(ctx, a.adminService.DisableUser, "success disable user")
+	}
+}
+
+func (a *adminHandler) EnableUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		a.userAction(ctx, a.adminService.EnableUser, "success enable user")
+	}
+}
+
+func (a *adminHandler) userAction(ctx *gin.Context, action func(ctx context.Context, admin *domain.User, userId uuid.UUID, reason string) domain.MessageErr, message string) {
+	userReq, _ := ctx.Get("userData")
+	admin := userReq.(*domain.User)
+
+	userId, err := uuid.Parse(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("user is not found"))
+		return
+	}
+
+	body := bindAdminActionRequest(ctx)
+
+	msgErr := action(ctx, admin, userId, body.Reason)
+	if msgErr != nil {
+		ctx.JSON(msgErr.Status(), msgErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func (a *adminHandler) HardDeleteCat() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		admin := userReq.(*domain.User)
+
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		body := bindAdminActionRequest(ctx)
+
+		msgErr := a.adminService.HardDeleteCat(ctx, admin, catId, body.Reason)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success delete cat"})
+	}
+}
+
+func (a *adminHandler) CancelCatMatch() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		admin := userReq.(*domain.User)
+
+		matchId, err := uuid.Parse(ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("Cat match request is not found"))
+			return
+		}
+
+		body := bindAdminActionRequest(ctx)
+
+		msgErr := a.adminService.CancelCatMatch(ctx, admin, matchId, body.Reason)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success cancel cat match"})
+	}
+}
+
+// bindAdminActionRequest reads the optional reason for an admin action; an
+// empty body is fine.
+func bindAdminActionRequest(ctx *gin.Context) domain.AdminActionRequest {
+	var body domain.AdminActionRequest
+	_ = ctx.ShouldBindJSON(&body)
+	return body
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"cats-social/internal/audit"
+	"cats-social/internal/domain"
+	"cats-social/internal/repository"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func HandleCreateApiKey(db *sql.DB, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		var body domain.CreateApiKeyRequest
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+			return
+		}
+
+		if err := validateApiKeyRequest(body); err != nil {
+			ctx.JSON(err.Status(), err)
+			return
+		}
+
+		apiKey, plain, err := domain.NewApiKey(user.Id, body.Name, body.Scopes)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = repository.NewApiKeyRepository().CreateApiKey(db, apiKey)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventApiKeyCreated, user.Id, map[string]any{
+			"apiKeyId": apiKey.ID,
+			"prefix":   apiKey.Prefix,
+			"scopes":   apiKey.Scopes,
+		}))
+
+		res := &domain.CreateApiKeyResponse{
+			ApiKeyResponse: domain.NewApiKeyResponse(apiKey),
+			Key:            plain,
+		}
+
+		ctx.JSON(http.StatusCreated, domain.NewStatusCreated("Api key created, it will not be shown again", res))
+	}
+}
+
+func HandleGetApiKeys(db *sql.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		apiKeys, err := repository.NewApiKeyRepository().GetApiKeysByUserId(db, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		res := []domain.ApiKeyResponse{}
+		for _, apiKey := range apiKeys {
+			res = append(res, domain.NewApiKeyResponse(&apiKey))
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", res))
+	}
+}
+
+func HandleRevokeApiKey(db *sql.DB, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		id, err := uuid.Parse(ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("api key is not found"))
+			return
+		}
+
+		revoked, err := repository.NewApiKeyRepository().RevokeApiKey(db, id, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if !revoked {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("api key is not found"))
+			return
+		}
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventApiKeyRevoked, user.Id, map[string]any{
+			"apiKeyId": id,
+		}))
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success revoke api key"})
+	}
+}
+
+func validateApiKeyRequest(body domain.CreateApiKeyRequest) domain.MessageErr {
+	if len(body.Name) < 1 || len(body.Name) > 64 {
+		return domain.NewBadRequest("name length should be between 1 and 64 characters")
+	}
+
+	if len(body.Scopes) < 1 {
+		return domain.NewBadRequest("scopes at least have 1 scope")
+	}
+
+	for _, scope := range body.Scopes {
+		if !slices.Contains(domain.ApiKeyScopes, scope) {
+			return domain.NewBadRequest(fmt.Sprintf("accepted scopes are only %s", strings.Join(domain.ApiKeyScopes, ", ")))
+		}
+	}
+
+	return nil
+}
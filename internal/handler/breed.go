@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"cats-social/internal/domain"
+	"cats-social/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BreedHandler interface {
+	GetBreeds() gin.HandlerFunc
+	CreateBreed() gin.HandlerFunc
+	UpdateBreed() gin.HandlerFunc
+	DeleteBreed() gin.HandlerFunc
+}
+
+type breedHandler struct {
+	breedService service.BreedService
+}
+
+func NewBreedHandler(breedService service.BreedService) BreedHandler {
+	return &breedHandler{
+		breedService: breedService,
+	}
+}
+
+func (b *breedHandler) GetBreeds() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		breeds, msgErr := b.breedService.GetBreeds()
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", breeds))
+	}
+}
+
+func (b *breedHandler) CreateBreed() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		admin := userReq.(*domain.User)
+
+		body, ok := bindBreedRequest(ctx)
+		if !ok {
+			return
+		}
+
+		breed, msgErr := b.breedService.CreateBreed(ctx, admin, body)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, domain.NewStatusOk("success", breed))
+	}
+}
+
+func (b *breedHandler) UpdateBreed() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		admin := userReq.(*domain.User)
+
+		breedId, err := uuid.Parse(ctx.Param("breedId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("breed is not found"))
+			return
+		}
+
+		body, ok := bindBreedRequest(ctx)
+		if !ok {
+			return
+		}
+
+		breed, msgErr := b.breedService.UpdateBreed(ctx, admin, breedId, body)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", breed))
+	}
+}
+
+func (b *breedHandler) DeleteBreed() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		admin := userReq.(*domain.User)
+
+		breedId, err := uuid.Parse(ctx.Param("breedId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("breed is not found"))
+			return
+		}
+
+		body := bindAdminActionRequest(ctx)
+
+		msgErr := b.breedService.DeleteBreed(ctx, admin, breedId, body.Reason)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success delete breed"})
+	}
+}
+
+// bindBreedRequest reads and validates the body of a breed, answering the
+// request itself when it is not valid.
+func bindBreedRequest(ctx *gin.Context) (domain.BreedRequest, bool) {
+	var body domain.BreedRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+		return body, false
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	for i := range body.Aliases {
+		body.Aliases[i] = strings.TrimSpace(body.Aliases[i])
+	}
+	body.Origin = strings.TrimSpace(body.Origin)
+
+	if err := validateBreedRequest(body); err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+		return body, false
+	}
+
+	return body, true
+}
+
+func validateBreedRequest(body domain.BreedRequest) error {
+	if len(body.Name) < 1 || len(body.Name) > domain.BreedNameMaxLength {
+		return fmt.Errorf("name length should be between 1 and %d characters", domain.BreedNameMaxLength)
+	}
+
+	if len(body.Aliases) > domain.BreedMaxAliases {
+		return fmt.Errorf("a breed can have at most %d aliases", domain.BreedMaxAliases)
+	}
+	seen := map[string]bool{strings.ToLower(body.Name): true}
+	for _, alias := range body.Aliases {
+		if len(alias) < 1 || len(alias) > domain.BreedNameMaxLength {
+			return fmt.Errorf("alias length should be between 1 and %d characters", domain.BreedNameMaxLength)
+		}
+		if seen[strings.ToLower(alias)] {
+			return fmt.Errorf("%s is given twice", alias)
+		}
+		seen[strings.ToLower(alias)] = true
+	}
+
+	if body.Size != "" && !slices.Contains(domain.BreedSizes, body.Size) {
+		return errors.New("accepted size is only small, medium and large")
+	}
+
+	if body.Coat != "" && !slices.Contains(domain.BreedCoats, body.Coat) {
+		return errors.New("accepted coat is only hairless, short, medium and long")
+	}
+
+	if len(body.Origin) > domain.BreedOriginMaxLength {
+		return fmt.Errorf("origin length should be at most %d characters", domain.BreedOriginMaxLength)
+	}
+
+	return nil
+}
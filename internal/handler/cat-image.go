@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"cats-social/internal/domain"
+	"cats-social/internal/service"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// catImageFormField is the multipart field holding the uploaded files.
+const catImageFormField = "images"
+
+type CatImageHandler interface {
+	GetCatImages() gin.HandlerFunc
+	UploadCatImages() gin.HandlerFunc
+	ReorderCatImages() gin.HandlerFunc
+	DeleteCatImage() gin.HandlerFunc
+}
+
+type catImageHandler struct {
+	catImageService service.CatImageService
+}
+
+func NewCatImageHandler(catImageService service.CatImageService) CatImageHandler {
+	return &catImageHandler{
+		catImageService: catImageService,
+	}
+}
+
+func (c *catImageHandler) GetCatImages() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		images, msgErr := c.catImageService.GetCatImages(ctx, user, catId)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", images))
+	}
+}
+
+func (c *catImageHandler) UploadCatImages() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		// room for a full set of images plus the multipart framing
+		maxBody := int64(domain.CatImageMaxPerCat*domain.CatImageMaxBytes + 1<<20)
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBody)
+
+		form, err := ctx.MultipartForm()
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("request body is too large"))
+				return
+			}
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(fmt.Sprintf("body should be multipart/form-data with %q files", catImageFormField)))
+			return
+		}
+		defer form.RemoveAll()
+
+		headers := form.File[catImageFormField]
+		if len(headers) < 1 {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("images at least have 1 image"))
+			return
+		}
+		if len(headers) > domain.CatImageMaxPerCat {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(fmt.Sprintf("a cat can have at most %d images", domain.CatImageMaxPerCat)))
+			return
+		}
+
+		files := make([][]byte, 0, len(headers))
+		for i, header := range headers {
+			if header.Size > domain.CatImageMaxBytes {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(fmt.Sprintf("image %d: image should be at most %d MB", i+1, domain.CatImageMaxBytes>>20)))
+				return
+			}
+
+			file, err := header.Open()
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(fmt.Sprintf("image %d cannot be read", i+1)))
+				return
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(fmt.Sprintf("image %d cannot be read", i+1)))
+				return
+			}
+
+			files = append(files, data)
+		}
+
+		images, msgErr := c.catImageService.UploadCatImages(ctx, user, catId, files)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, gin.H{"message": "success", "data": images})
+	}
+}
+
+func (c *catImageHandler) ReorderCatImages() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		var body domain.ReorderCatImagesRequest
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("imageIds should be a list of image ids"))
+			return
+		}
+
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		images, msgErr := c.catImageService.ReorderCatImages(ctx, user, catId, body.ImageIds)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", images))
+	}
+}
+
+func (c *catImageHandler) DeleteCatImage() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+		imageId, err := uuid.Parse(ctx.Param("imageId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("image is not found"))
+			return
+		}
+
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		msgErr := c.catImageService.DeleteCatImage(ctx, user, catId, imageId)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success delete cat image"})
+	}
+}
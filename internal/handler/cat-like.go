@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"cats-social/internal/domain"
+	"cats-social/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CatLikeHandler interface {
+	LikeCat() gin.HandlerFunc
+	UnlikeCat() gin.HandlerFunc
+	GetLikedCats() gin.HandlerFunc
+	GetCatLikers() gin.HandlerFunc
+}
+
+type catLikeHandler struct {
+	catLikeService service.CatLikeService
+}
+
+func NewCatLikeHandler(catLikeService service.CatLikeService) CatLikeHandler {
+	return &catLikeHandler{
+		catLikeService: catLikeService,
+	}
+}
+
+func (c *catLikeHandler) LikeCat() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		status, msgErr := c.catLikeService.LikeCat(ctx, user, catId)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", status))
+	}
+}
+
+func (c *catLikeHandler) UnlikeCat() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		status, msgErr := c.catLikeService.UnlikeCat(ctx, user, catId)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", status))
+	}
+}
+
+func (c *catLikeHandler) GetLikedCats() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		page, msgErr := parsePageRequest(ctx.Request.URL.Query(), domain.CatLikeListDefaultLimit, domain.CatLikeListMaxLimit, domain.CatLikeSortLikedAt)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		cats, pagination, msgErr := c.catLikeService.GetLikedCats(ctx, user, page)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success", "data": cats, "pagination": pagination})
+	}
+}
+
+func (c *catLikeHandler) GetCatLikers() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		page, msgErr := parsePageRequest(ctx.Request.URL.Query(), domain.CatLikeListDefaultLimit, domain.CatLikeListMaxLimit, domain.CatLikeSortLikedAt)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		likers, pagination, msgErr := c.catLikeService.GetCatLikers(ctx, user, catId, page)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success", "data": likers, "pagination": pagination})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,6 +19,7 @@ type CatMatchHandler interface {
 	DeleteCatMatchByID() gin.HandlerFunc
 	ApproveCatMatch() gin.HandlerFunc
 	RejectCatMatch() gin.HandlerFunc
+	GetRecommendations() gin.HandlerFunc
 }
 
 type catMatchHandler struct {
@@ -61,15 +64,21 @@ func (c *catMatchHandler) CreateCatMatch() gin.HandlerFunc {
 		catMatch := domain.NewCatMatchFromBody(body)
 		catMatch.IssuedByID = user.Id
 
-		err := c.catMatchService.CreateCatMatch(ctx, user, catMatch)
-		if err, ok := err.(domain.MessageErr); ok {
+		warnings, err := c.catMatchService.CreateCatMatch(ctx, user, catMatch)
+		if err != nil {
 			ctx.JSON(err.Status(), err)
 			return
 		}
 
-		ctx.JSON(http.StatusCreated, gin.H{
+		res := gin.H{
 			"message": "success create cat match",
-		})
+		}
+		// related cats, when the inbreeding policy only warns
+		if len(warnings) > 0 {
+			res["warnings"] = warnings
+		}
+
+		ctx.JSON(http.StatusCreated, res)
 	}
 }
 
@@ -78,7 +87,13 @@ func (c *catMatchHandler) GetCatMatchesByIssuerOrReceiverID() gin.HandlerFunc {
 		userReq, _ := ctx.Get("userData")
 		user := userReq.(*domain.User)
 
-		result, err := c.catMatchService.GetCatMatchesByIssuerOrReceiverID(ctx, user.Id.String())
+		page, err := parsePageRequest(ctx.Request.URL.Query(), domain.CatMatchListDefaultLimit, domain.CatMatchListMaxLimit, domain.CatSortCreatedAt)
+		if err != nil {
+			ctx.JSON(err.Status(), err)
+			return
+		}
+
+		result, pagination, err := c.catMatchService.GetCatMatchesByIssuerOrReceiverID(ctx, user.Id.String(), page)
 		if err != nil {
 			ctx.JSON(err.Status(), gin.H{
 				"message": err.Message(),
@@ -88,8 +103,9 @@ func (c *catMatchHandler) GetCatMatchesByIssuerOrReceiverID() gin.HandlerFunc {
 		}
 
 		ctx.JSON(200, gin.H{
-			"data":    result,
-			"message": "success",
+			"data":       result,
+			"pagination": pagination,
+			"message":    "success",
 		})
 	}
 }
@@ -189,3 +205,64 @@ func (c *catMatchHandler) RejectCatMatch() gin.HandlerFunc {
 		})
 	}
 }
+
+func (c *catMatchHandler) GetRecommendations() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		prefs, msgErr := parseRecommendationPreferences(ctx.Request.URL.Query())
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		recommendations, msgErr := c.catMatchService.GetRecommendations(ctx, user, catId, prefs)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", recommendations))
+	}
+}
+
+// parseRecommendationPreferences reads limit and the preferred races of the
+// recommendations. Unknown params are rejected, races are checked against
+// the breed catalogue by the service.
+func parseRecommendationPreferences(query url.Values) (domain.RecommendationPreferences, domain.MessageErr) {
+	prefs := domain.RecommendationPreferences{Limit: domain.CatRecommendationDefaultLimit}
+
+	for key, values := range query {
+		if strings.Join(values, "") == "" {
+			continue
+		}
+
+		switch key {
+		case "limit":
+			if len(values) > 1 {
+				return prefs, domain.NewBadRequest("limit should be given once")
+			}
+			limit, msgErr := parseLimit(values[0], domain.CatRecommendationMaxLimit)
+			if msgErr != nil {
+				return prefs, msgErr
+			}
+			prefs.Limit = limit
+		case "race":
+			prefs.Races = append(prefs.Races, splitQueryValues(values)...)
+		default:
+			return prefs, domain.NewBadRequest(fmt.Sprintf("unknown query param %s", key))
+		}
+	}
+
+	return prefs, nil
+}
@@ -4,10 +4,15 @@ import (
 	"cats-social/internal/domain"
 	"cats-social/internal/service"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,6 +21,9 @@ import (
 type CatHandler interface {
 	CreateCat() gin.HandlerFunc
 	GetAllCats() gin.HandlerFunc
+	GetCat() gin.HandlerFunc
+	GetTraits() gin.HandlerFunc
+	GetPedigree() gin.HandlerFunc
 	UpdateCat() gin.HandlerFunc
 	DeleteCat() gin.HandlerFunc
 }
@@ -48,8 +56,10 @@ func (c *catHandler) CreateCat() gin.HandlerFunc {
 		}
 
 		catBody.OwnedById = user.Id
+		// images are uploaded separately, see CatImageHandler
+		catBody.ImageUrls = []string{}
 
-		err = c.catSerivce.CreateCat(catBody)
+		err = c.catSerivce.CreateCat(ctx, catBody)
 		if err != nil {
 			err, _ := err.(domain.MessageErr)
 			ctx.JSON(err.Status(), err)
@@ -73,7 +83,13 @@ func (c *catHandler) GetAllCats() gin.HandlerFunc {
 		userReq, _ := ctx.Get("userData")
 		user := userReq.(*domain.User)
 
-		cats, err := c.catSerivce.GetAllCats(user, ctx.Request.URL.Query())
+		filter, err := parseCatFilter(ctx.Request.URL.Query())
+		if err != nil {
+			ctx.JSON(err.Status(), err)
+			return
+		}
+
+		cats, pagination, err := c.catSerivce.GetAllCats(user, filter)
 		if err != nil {
 			ctx.JSON(err.Status(), err)
 			if err.Status() > 499 {
@@ -82,7 +98,76 @@ func (c *catHandler) GetAllCats() gin.HandlerFunc {
 			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "success", "data": &cats})
+		ctx.JSON(http.StatusOK, gin.H{"message": "success", "data": &cats, "pagination": pagination})
+	}
+}
+
+func (c *catHandler) GetCat() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		cat, msgErr := c.catSerivce.GetCat(user, catId)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", cat))
+	}
+}
+
+func (c *catHandler) GetTraits() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		categories, msgErr := c.catSerivce.GetTraitCategories()
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", categories))
+	}
+}
+
+func (c *catHandler) GetPedigree() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		catId, err := uuid.Parse(ctx.Param("catId"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("cat is not found"))
+			return
+		}
+
+		generations := domain.CatPedigreeDefaultGenerations
+		if value := ctx.Query("generations"); value != "" {
+			generations, err = strconv.Atoi(value)
+			if err != nil || generations < 1 || generations > domain.CatPedigreeMaxGenerations {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(fmt.Sprintf("generations should be between 1 and %d", domain.CatPedigreeMaxGenerations)))
+				return
+			}
+		}
+
+		pedigree, msgErr := c.catSerivce.GetPedigree(catId, generations)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", pedigree))
 	}
 }
 
@@ -113,7 +198,7 @@ func (c *catHandler) UpdateCat() gin.HandlerFunc {
 
 		catBody.ID = parsedCatId
 
-		err = c.catSerivce.UpdateCat(user, catBody)
+		err = c.catSerivce.UpdateCat(ctx, user, catBody)
 		if err != nil {
 			err, _ := err.(domain.MessageErr)
 			ctx.JSON(err.Status(), err)
@@ -147,7 +232,14 @@ func (c *catHandler) DeleteCat() gin.HandlerFunc {
 		userReq, _ := ctx.Get("userData")
 		user := userReq.(*domain.User)
 
-		err = c.catSerivce.DeleteCat(user, parsedCatId)
+		msgErr := c.catSerivce.DeleteCat(user, parsedCatId)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			if msgErr.Status() > 499 {
+				panic(msgErr)
+			}
+			return
+		}
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "success delete cat"})
 	}
@@ -159,8 +251,8 @@ func validateRequestBody(body domain.Cat) error {
 		return err
 	}
 
-	if slices.Contains(domain.CatRace, body.Race) != true {
-		err := errors.New("accepted race is only Persian, Maine Coon, Siamese, Ragdoll, Bengal, Sphynx, British Shorthair, Abyssinian, Scottish Fold, Birman")
+	if len(body.Race) < 1 {
+		err := errors.New("race is required")
 		return err
 	}
 
@@ -179,22 +271,272 @@ func validateRequestBody(body domain.Cat) error {
 		return err
 	}
 
-	if len(body.ImageUrls) < 1 {
-		err := errors.New("image urls at least have 1 image")
-		return err
-	}
+	if body.Location != nil {
+		location := body.Location
+		if location.Latitude == nil || location.Longitude == nil {
+			err := errors.New("location needs both latitude and longitude")
+			return err
+		}
 
-	for _, imageUrl := range body.ImageUrls {
-		if len(imageUrl) < 1 {
-			err := errors.New("image urls cannot have empty item")
+		if !validCoordinates(*location.Latitude, *location.Longitude) {
+			err := errors.New("latitude should be between -90 and 90 and longitude between -180 and 180")
 			return err
 		}
 
-		_, err := url.ParseRequestURI(imageUrl)
-		if err != nil {
-			err := errors.New("image url should have valid url")
+		if len(location.City) > 60 {
+			err := errors.New("city length should be at most 60 characters")
 			return err
 		}
 	}
+
 	return nil
 }
+
+var ageComparisonPattern = regexp.MustCompile(`^(>=|<=|>|<|=)?(\d+)$`)
+
+// parseCatFilter validates the query of the cat listing. Unknown params and
+// bad values are rejected rather than ignored.
+func parseCatFilter(query url.Values) (*domain.CatFilter, domain.MessageErr) {
+	filter := domain.NewCatFilter()
+	orderGiven := false
+
+	for key, values := range query {
+		if !slices.Contains(domain.CatQueryParams, key) {
+			return nil, domain.NewBadRequest(fmt.Sprintf("unknown query param %s", key))
+		}
+		// an empty param is the same as a missing one
+		if strings.Join(values, "") == "" {
+			continue
+		}
+
+		// race, sex and trait take several values, ageInMonth several bounds
+		multiple := key == "race" || key == "sex" || key == "trait" || key == "ageInMonth"
+		if len(values) > 1 && !multiple {
+			return nil, domain.NewBadRequest(fmt.Sprintf("%s should be given once", key))
+		}
+		value := values[0]
+
+		switch key {
+		case "id":
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return nil, domain.NewBadRequest("id should be a uuid")
+			}
+			filter.ID = &id
+		case "race":
+			// checked against the breed catalogue by the service
+			filter.Races = append(filter.Races, splitQueryValues(values)...)
+		case "sex":
+			for _, sex := range splitQueryValues(values) {
+				if !slices.Contains(domain.CatSex, sex) {
+					return nil, domain.NewBadRequest("accepted sex is only male and female")
+				}
+				filter.Sexes = append(filter.Sexes, sex)
+			}
+		case "trait":
+			// checked against the vocabulary by the service
+			for _, trait := range splitQueryValues(values) {
+				if !slices.Contains(filter.Traits, trait) {
+					filter.Traits = append(filter.Traits, trait)
+				}
+			}
+		case "traitMatch":
+			if value != domain.CatTraitMatchAll && value != domain.CatTraitMatchAny {
+				return nil, domain.NewBadRequest("traitMatch should be all or any")
+			}
+			filter.TraitsMatchAny = value == domain.CatTraitMatchAny
+		case "hasMatched", "owned", "liked":
+			if value != "true" && value != "false" {
+				return nil, domain.NewBadRequest(fmt.Sprintf("%s should be true or false", key))
+			}
+			b := value == "true"
+			switch key {
+			case "hasMatched":
+				filter.HasMatched = &b
+			case "owned":
+				filter.Owned = &b
+			default:
+				filter.Liked = &b
+			}
+		case "ageInMonth":
+			for _, value := range values {
+				if value == "" {
+					continue
+				}
+				matches := ageComparisonPattern.FindStringSubmatch(value)
+				if matches == nil {
+					return nil, domain.NewBadRequest("ageInMonth should be a number with an optional >, >=, <, <= or = in front")
+				}
+
+				age, err := strconv.ParseInt(matches[2], 10, 32)
+				if err != nil {
+					return nil, domain.NewBadRequest("ageInMonth is too large")
+				}
+
+				operator := matches[1]
+				if operator == "" {
+					operator = "="
+				}
+				filter.Ages = append(filter.Ages, domain.AgeComparison{Operator: operator, Value: int32(age)})
+			}
+		case "search":
+			filter.Search = strings.TrimSpace(value)
+		case "createdAfter", "createdBefore":
+			t, err := parseQueryTime(value)
+			if err != nil {
+				return nil, domain.NewBadRequest(fmt.Sprintf("%s should be a date (2006-01-02) or an RFC 3339 time", key))
+			}
+			if key == "createdAfter" {
+				filter.CreatedAfter = &t
+			} else {
+				filter.CreatedBefore = &t
+			}
+		case "near":
+			near, err := parseGeoPoint(value)
+			if err != nil {
+				return nil, domain.NewBadRequest("near should be a latitude and longitude, e.g. -6.2,106.8")
+			}
+			filter.Near = near
+		case "radiusKm":
+			radius, err := strconv.ParseFloat(value, 64)
+			if err != nil || !(radius > 0 && radius <= domain.NearMaxRadiusKm) {
+				return nil, domain.NewBadRequest(fmt.Sprintf("radiusKm should be more than 0 and at most %d", domain.NearMaxRadiusKm))
+			}
+			filter.RadiusKm = radius
+		case "sort":
+			if !slices.Contains(domain.CatSorts, value) {
+				return nil, domain.NewBadRequest("sort should be name, ageInMonth, createdAt or distance")
+			}
+			filter.Sort = value
+		case "order":
+			if value != "asc" && value != "desc" {
+				return nil, domain.NewBadRequest("order should be asc or desc")
+			}
+			filter.SortDesc = value == "desc"
+			orderGiven = true
+		case "limit":
+			limit, msgErr := parseLimit(value, domain.CatListMaxLimit)
+			if msgErr != nil {
+				return nil, msgErr
+			}
+			filter.Limit = limit
+		case "cursor":
+			cursor, msgErr := parseCursor(value)
+			if msgErr != nil {
+				return nil, msgErr
+			}
+			filter.Cursor = cursor
+		case "includeTotal":
+			includeTotal, msgErr := parseIncludeTotal(value)
+			if msgErr != nil {
+				return nil, msgErr
+			}
+			filter.IncludeTotal = includeTotal
+		case "offset":
+			offset, err := strconv.Atoi(value)
+			if err != nil || offset < 0 {
+				return nil, domain.NewBadRequest("offset should be 0 or more")
+			}
+			filter.Offset = offset
+		}
+	}
+
+	if orderGiven && filter.Sort == "" {
+		return nil, domain.NewBadRequest("order needs sort")
+	}
+	// newest first by default, names, ages and distances ascending
+	if !orderGiven {
+		filter.SortDesc = filter.Sort == domain.CatSortCreatedAt
+	}
+	if filter.Near == nil && filter.RadiusKm != 0 {
+		return nil, domain.NewBadRequest("radiusKm needs near")
+	}
+	if filter.Near != nil && filter.RadiusKm == 0 {
+		filter.RadiusKm = domain.NearDefaultRadiusKm
+	}
+	if filter.Near == nil && filter.Sort == domain.CatSortDistance {
+		return nil, domain.NewBadRequest("sort distance needs near")
+	}
+	if filter.Sort == "" {
+		switch {
+		case strings.IndexFunc(filter.Search, isWordRune) >= 0:
+			filter.Sort, filter.SortDesc = domain.CatSortRelevance, true
+		case filter.Near != nil:
+			filter.Sort, filter.SortDesc = domain.CatSortDistance, false
+		default:
+			filter.Sort, filter.SortDesc = domain.CatSortCreatedAt, true
+		}
+	}
+
+	if filter.Cursor != nil {
+		if filter.Offset > 0 {
+			return nil, domain.NewBadRequest("cursor and offset cannot be used together")
+		}
+		// a cursor only makes sense in the listing it came from
+		if filter.Cursor.Sort != filter.Sort || filter.Cursor.Desc != filter.SortDesc {
+			return nil, domain.NewBadRequest("cursor does not belong to this sort")
+		}
+		if _, err := domain.CatCursorValue(filter.Cursor); err != nil {
+			return nil, domain.NewBadRequest("cursor is invalid")
+		}
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return nil, domain.NewBadRequest("createdAfter should be before createdBefore")
+	}
+
+	return filter, nil
+}
+
+// isWordRune tells whether a search has something to search for.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// splitQueryValues accepts both race=Persian,Bengal and
+// race=Persian&race=Bengal.
+func splitQueryValues(values []string) []string {
+	res := []string{}
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				res = append(res, part)
+			}
+		}
+	}
+
+	return res
+}
+
+// parseGeoPoint reads "latitude,longitude".
+func parseGeoPoint(value string) (*domain.GeoPoint, error) {
+	latitude, longitude, found := strings.Cut(value, ",")
+	if !found {
+		return nil, errors.New("missing longitude")
+	}
+
+	point := domain.GeoPoint{}
+	var err error
+	if point.Latitude, err = strconv.ParseFloat(strings.TrimSpace(latitude), 64); err != nil {
+		return nil, err
+	}
+	if point.Longitude, err = strconv.ParseFloat(strings.TrimSpace(longitude), 64); err != nil {
+		return nil, err
+	}
+	if !validCoordinates(point.Latitude, point.Longitude) {
+		return nil, errors.New("coordinates out of range")
+	}
+
+	return &point, nil
+}
+
+func validCoordinates(latitude float64, longitude float64) bool {
+	return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
+}
+
+func parseQueryTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"cats-social/internal/domain"
+	"cats-social/internal/mail"
+	"cats-social/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HandleVerifyEmail(db *sql.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.Query("token")
+		if len(token) < 1 {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("token not be empty"))
+			return
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		defer tx.Rollback()
+
+		emailVerificationRepository := repository.NewEmailVerificationRepository()
+
+		userId, err := emailVerificationRepository.ConsumeToken(ctx, tx, domain.HashOpaqueToken(token))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("invalid or expired verification token"))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = emailVerificationRepository.MarkEmailVerified(ctx, tx, userId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+	}
+}
+
+func HandleResendVerificationEmail(db *sql.DB, mailer mail.Sender) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		if user.IsEmailVerified() {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("email already verified"))
+			return
+		}
+
+		err := sendVerificationEmail(ctx, db, mailer, user)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Verification email has been sent"})
+	}
+}
+
+// sendVerificationEmail replaces any pending verification token of the user
+// with a new one and mails it.
+func sendVerificationEmail(ctx context.Context, db *sql.DB, mailer mail.Sender, user *domain.User) error {
+	token, hash, err := domain.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	verificationToken := domain.NewEmailVerificationToken(user.Id)
+	verificationToken.TokenHash = hash
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	emailVerificationRepository := repository.NewEmailVerificationRepository()
+
+	err = emailVerificationRepository.InvalidateUserTokens(ctx, tx, user.Id)
+	if err != nil {
+		return err
+	}
+
+	err = emailVerificationRepository.CreateToken(ctx, tx, verificationToken)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your Cats Social email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %d hours.\n\n%s%s\n",
+			user.Name, int(domain.EmailVerificationTokenTTL.Hours()), os.Getenv("EMAIL_VERIFICATION_URL"), token,
+		),
+	})
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"cats-social/internal/keyring"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HandleJWKS(kr *keyring.Keyring) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, kr.JWKS())
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"cats-social/internal/audit"
+	"cats-social/internal/domain"
+	"cats-social/internal/oidc"
+	"cats-social/internal/repository"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func HandleOIDCStart(db *sql.DB, provider *oidc.Provider) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		state, err := oidc.NewState()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		nonce, err := oidc.NewState()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		codeVerifier, codeChallenge, err := oidc.NewPKCE()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = repository.NewIdentityRepository().CreateLoginState(db, domain.NewOIDCLoginState(state, codeVerifier, nonce))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		ctx.Redirect(http.StatusFound, provider.AuthCodeURL(state, nonce, codeChallenge))
+	}
+}
+
+func HandleOIDCCallback(db *sql.DB, provider *oidc.Provider, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		invalidLoginErr := domain.NewUnauthenticatedError("invalid or expired login, start again")
+
+		if providerErr := ctx.Query("error"); providerErr != "" {
+			ctx.JSON(http.StatusUnauthorized, domain.NewUnauthenticatedError("identity provider returned "+providerErr))
+			return
+		}
+
+		state := ctx.Query("state")
+		code := ctx.Query("code")
+		if len(state) < 1 || len(code) < 1 {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("state and code not be empty"))
+			return
+		}
+
+		identityRepository := repository.NewIdentityRepository()
+
+		loginState, err := identityRepository.ConsumeLoginState(db, domain.HashOpaqueToken(state))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(invalidLoginErr.Status(), invalidLoginErr)
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		rawIDToken, err := provider.Exchange(ctx, code, loginState.CodeVerifier)
+		if err != nil {
+			log.Printf("oidc code exchange failed: %s", err)
+			ctx.JSON(invalidLoginErr.Status(), invalidLoginErr)
+			return
+		}
+
+		claims, err := provider.VerifyIDToken(ctx, rawIDToken, loginState.Nonce)
+		if err != nil {
+			log.Printf("oidc id token rejected: %s", err)
+			ctx.JSON(invalidLoginErr.Status(), invalidLoginErr)
+			return
+		}
+
+		user, err := userForIdentity(ctx, db, provider.Issuer(), claims)
+		if err != nil {
+			var messageErr domain.MessageErr
+			if errors.As(err, &messageErr) {
+				ctx.JSON(messageErr.Status(), messageErr)
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if user.IsDisabled() {
+			ctx.JSON(http.StatusForbidden, domain.NewUnauthorizedError("account has been disabled"))
+			return
+		}
+		user.TokenService = domain.NewTokenService()
+
+		completeLogin(ctx, db, recorder, user, "oidc")
+	}
+}
+
+// userForIdentity returns the user linked to the identity. An unlinked
+// identity is linked by its verified email to an existing user, or to a new
+// user when nobody has registered that email yet.
+func userForIdentity(ctx *gin.Context, db *sql.DB, issuer string, claims *oidc.Claims) (*domain.User, error) {
+	userRepository := repository.NewUserPg()
+	identityRepository := repository.NewIdentityRepository()
+
+	userId, err := identityRepository.GetUserIdByIdentity(db, issuer, claims.Subject)
+	if err == nil {
+		user, err := userRepository.GetById(db, userId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewUnauthenticatedError("account has been deleted")
+		}
+		return user, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if !claims.EmailVerified || !validEmail(claims.Email) {
+		return nil, domain.NewUnauthenticatedError("identity provider did not return a verified email")
+	}
+
+	user, err := userRepository.GetByEmail(db, claims.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// linking to an unverified account would let whoever registered the
+	// email first sign in to the provider's account with their password
+	if user != nil && !user.IsEmailVerified() {
+		return nil, domain.NewConflictError("an account with this email exists, verify its email before signing in with the identity provider")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if user == nil {
+		user = domain.NewUser()
+		user.Email = claims.Email
+		user.Name = oidcUserName(claims)
+		// the account has no usable password until the user resets it
+		user.Password = uuid.NewString()
+		if err := user.HashPassword(); err != nil {
+			return nil, err
+		}
+
+		err = userRepository.CreateVerifiedUser(ctx, tx, user)
+		if errors.Is(err, repository.ErrEmailTaken) {
+			// registered meanwhile
+			return nil, domain.NewConflictError("an account with this email exists")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = identityRepository.CreateIdentity(ctx, tx, domain.NewUserIdentity(user.Id, issuer, claims.Subject, claims.Email))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return userRepository.GetById(db, user.Id)
+}
+
+// oidcUserName fits the provider's name into the length register accepts,
+// falling back to the local part of the email.
+func oidcUserName(claims *oidc.Claims) string {
+	name := strings.TrimSpace(claims.Name)
+	if len(name) < 5 {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	for len(name) < 5 {
+		name += "_"
+	}
+	if len(name) > 50 {
+		name = name[:50]
+	}
+
+	return name
+}
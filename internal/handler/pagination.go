@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"cats-social/internal/domain"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+func parseLimit(value string, max int) (int, domain.MessageErr) {
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > max {
+		return 0, domain.NewBadRequest(fmt.Sprintf("limit should be between 1 and %d", max))
+	}
+
+	return limit, nil
+}
+
+func parseCursor(value string) (*domain.Cursor, domain.MessageErr) {
+	cursor, err := domain.DecodeCursor(value)
+	if err != nil {
+		return nil, domain.NewBadRequest("cursor is invalid")
+	}
+
+	return cursor, nil
+}
+
+func parseIncludeTotal(value string) (bool, domain.MessageErr) {
+	if value != "true" && value != "false" {
+		return false, domain.NewBadRequest("includeTotal should be true or false")
+	}
+
+	return value == "true", nil
+}
+
+// parsePageRequest reads limit, cursor and includeTotal of a listing that is
+// always sorted newest first by the time named sort.
+func parsePageRequest(query url.Values, defaultLimit int, maxLimit int, sort string) (domain.PageRequest, domain.MessageErr) {
+	page := domain.PageRequest{Limit: defaultLimit}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := parseLimit(value, maxLimit)
+		if err != nil {
+			return page, err
+		}
+		page.Limit = limit
+	}
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := parseCursor(value)
+		if err != nil {
+			return page, err
+		}
+		if cursor.Sort != sort || !cursor.Desc {
+			return page, domain.NewBadRequest("cursor does not belong to this sort")
+		}
+		if _, err := cursor.Time(); err != nil {
+			return page, domain.NewBadRequest("cursor is invalid")
+		}
+		page.Cursor = cursor
+	}
+	if value := query.Get("includeTotal"); value != "" {
+		includeTotal, err := parseIncludeTotal(value)
+		if err != nil {
+			return page, err
+		}
+		page.IncludeTotal = includeTotal
+	}
+
+	return page, nil
+}
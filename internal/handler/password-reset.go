@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"cats-social/internal/audit"
+	"cats-social/internal/domain"
+	"cats-social/internal/mail"
+	"cats-social/internal/passwordpolicy"
+	"cats-social/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// passwordResetTimeout bounds creating and mailing a reset link, which
+// happens after the request is answered.
+const passwordResetTimeout = 30 * time.Second
+
+func HandleForgotPassword(db *sql.DB, mailer mail.Sender, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// the response never tells whether the email is registered
+		res := gin.H{"message": "If the email is registered, a password reset link has been sent"}
+
+		var body domain.ForgotPasswordRequest
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+			return
+		}
+		if !validEmail(body.Email) {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("invalid email format"))
+			return
+		}
+
+		user, err := repository.NewUserPg().GetByEmail(db, body.Email)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusOK, res)
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventPasswordResetRequested, user.Id, nil))
+
+		// the link is created and mailed in the background, answering only
+		// once it is sent would tell registered emails apart by the time
+		// the request takes
+		go sendPasswordReset(db, mailer, user)
+
+		ctx.JSON(http.StatusOK, res)
+	}
+}
+
+// sendPasswordReset replaces the user's reset links with a new one and
+// mails it. It runs after the request has been answered, so failures are
+// only logged.
+func sendPasswordReset(db *sql.DB, mailer mail.Sender, user *domain.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetTimeout)
+	defer cancel()
+
+	token, hash, err := domain.NewOpaqueToken()
+	if err != nil {
+		log.Printf("failed to create password reset token: %s", err)
+		return
+	}
+
+	resetToken := domain.NewPasswordResetToken(user.Id)
+	resetToken.TokenHash = hash
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("failed to create password reset token: %s", err)
+		return
+	}
+	defer tx.Rollback()
+
+	passwordResetRepository := repository.NewPasswordResetRepository()
+
+	// only the most recently requested link stays usable
+	err = passwordResetRepository.InvalidateUserTokens(ctx, tx, user.Id)
+	if err != nil {
+		log.Printf("failed to create password reset token: %s", err)
+		return
+	}
+
+	err = passwordResetRepository.CreateToken(ctx, tx, resetToken)
+	if err != nil {
+		log.Printf("failed to create password reset token: %s", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("failed to create password reset token: %s", err)
+		return
+	}
+
+	err = mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Cats Social password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to reset your password. It expires in %d minutes and can only be used once.\n\n%s%s\n\nIf you did not request this, you can ignore this email.\n",
+			user.Name, int(domain.PasswordResetTokenTTL.Minutes()), os.Getenv("PASSWORD_RESET_URL"), token,
+		),
+	})
+	if err != nil {
+		log.Printf("failed to send password reset email: %s", err)
+	}
+}
+
+func HandleResetPassword(db *sql.DB, recorder audit.Recorder, passwordChecker *passwordpolicy.Checker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body domain.ResetPasswordRequest
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+			return
+		}
+		if len(body.Token) < 1 {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("token not be empty"))
+			return
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		defer tx.Rollback()
+
+		userId, err := repository.NewPasswordResetRepository().ConsumeToken(ctx, tx, domain.HashOpaqueToken(body.Token))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("invalid or expired reset token"))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		// a failed check rolls back, so the token can be used again
+		user, err := repository.NewUserPg().GetById(db, userId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("invalid or expired reset token"))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if err := checkPassword(ctx, passwordChecker, body.Password, user.Email, user.Name); err != nil {
+			ctx.JSON(err.Status(), err)
+			return
+		}
+
+		user.TokenService = domain.NewTokenService()
+		user.Password = body.Password
+		if err := user.HashPassword(); err != nil {
+			ctx.JSON(err.Status(), err)
+			return
+		}
+
+		err = repository.NewUserPg().UpdatePassword(ctx, tx, user.Id, user.Password)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = repository.NewSessionPg().RevokeUserSessions(ctx, tx, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventPasswordChanged, user.Id, map[string]any{
+			"via": "reset",
+		}))
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Password has been reset successfully"})
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"cats-social/internal/audit"
+	"cats-social/internal/domain"
+	"cats-social/internal/repository"
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func HandleGetSessions(db *sql.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		sessions, err := repository.NewSessionPg().GetActiveSessionsByUserId(db, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		res := []domain.SessionResponse{}
+		for _, session := range sessions {
+			res = append(res, domain.NewSessionResponse(&session, user.SessionId))
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", res))
+	}
+}
+
+// HandleRevokeSession signs out a single device. Revoking the current session
+// works like logout.
+func HandleRevokeSession(db *sql.DB, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		id, err := uuid.Parse(ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("session is not found"))
+			return
+		}
+
+		revoked, err := repository.NewSessionPg().RevokeUserSession(db, id, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if !revoked {
+			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("session is not found"))
+			return
+		}
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventSessionRevoked, user.Id, map[string]any{
+			"sessionId": id,
+		}))
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success revoke session"})
+	}
+}
+
+// HandleRevokeOtherSessions signs out every device except the one making the
+// request.
+func HandleRevokeOtherSessions(db *sql.DB, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		defer tx.Rollback()
+
+		err = repository.NewSessionPg().RevokeOtherUserSessions(ctx, tx, user.Id, user.SessionId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventSessionRevoked, user.Id, map[string]any{
+			"keptSessionId": user.SessionId,
+			"others":        true,
+		}))
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success revoke other sessions"})
+	}
+}
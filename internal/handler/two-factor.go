@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"cats-social/internal/audit"
+	"cats-social/internal/domain"
+	"cats-social/internal/loginguard"
+	"cats-social/internal/repository"
+	"cats-social/internal/totp"
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func HandleEnrollTwoFactor(db *sql.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		updated, err := repository.NewTwoFactorRepository().SetPendingSecret(db, user.Id, secret)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if !updated {
+			ctx.JSON(http.StatusConflict, domain.NewConflictError("two-factor authentication is already enabled"))
+			return
+		}
+
+		res := &domain.TwoFactorEnrollResponse{
+			Secret:          secret,
+			ProvisioningURI: totp.ProvisioningURI(domain.TwoFactorIssuer, user.Email, secret),
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("Scan the provisioning uri and confirm with a code", res))
+	}
+}
+
+func HandleConfirmTwoFactor(db *sql.DB, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		var body domain.TwoFactorCodeRequest
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+			return
+		}
+
+		twoFactorRepository := repository.NewTwoFactorRepository()
+
+		twoFactor, err := twoFactorRepository.GetTwoFactor(db, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if twoFactor.EnabledAt != nil {
+			ctx.JSON(http.StatusConflict, domain.NewConflictError("two-factor authentication is already enabled"))
+			return
+		}
+		if twoFactor.Secret == nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("enroll two-factor authentication first"))
+			return
+		}
+
+		step, ok := totp.Validate(*twoFactor.Secret, body.Code, time.Now())
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("invalid code"))
+			return
+		}
+
+		codes, hashes, err := domain.NewRecoveryCodes()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		defer tx.Rollback()
+
+		err = twoFactorRepository.EnableTwoFactor(ctx, tx, user.Id, step)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = twoFactorRepository.ReplaceRecoveryCodes(ctx, tx, user.Id, hashes)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventTwoFactorEnabled, user.Id, nil))
+
+		res := &domain.TwoFactorConfirmResponse{
+			RecoveryCodes: codes,
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("Two-factor authentication enabled, store the recovery codes safely", res))
+	}
+}
+
+func HandleDisableTwoFactor(db *sql.DB, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		var body domain.DisableTwoFactorRequest
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+			return
+		}
+
+		dbUser, err := repository.NewUserPg().GetById(db, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if !dbUser.ComparePassword(body.Password) {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("current password is wrong"))
+			return
+		}
+
+		twoFactorRepository := repository.NewTwoFactorRepository()
+
+		twoFactor, err := twoFactorRepository.GetTwoFactor(db, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if twoFactor.EnabledAt == nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("two-factor authentication is not enabled"))
+			return
+		}
+
+		valid, err := verifySecondFactor(db, user.Id, twoFactor, body.Code)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if !valid {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("invalid code"))
+			return
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		defer tx.Rollback()
+
+		err = twoFactorRepository.DisableTwoFactor(ctx, tx, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventTwoFactorDisabled, user.Id, nil))
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
+
+func HandleLoginTwoFactor(db *sql.DB, tracker loginguard.Tracker, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body domain.LoginTwoFactorRequest
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+			return
+		}
+
+		userId, msgErr := domain.ParseTwoFactorChallenge(body.ChallengeToken)
+		if msgErr != nil {
+			ctx.JSON(msgErr.Status(), msgErr)
+			return
+		}
+
+		// codes only have a million values, so guesses are throttled per user
+		key := "2fa:" + userId.String()
+		retryAfter, err := tracker.Locked(ctx, key)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if retryAfter > 0 {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			tooManyAttemptsErr := domain.NewTooManyRequestsError("too many login attempts, try again later")
+			ctx.JSON(tooManyAttemptsErr.Status(), tooManyAttemptsErr)
+			return
+		}
+
+		user, err := repository.NewUserPg().GetById(db, userId)
+		if err != nil || user.IsDisabled() {
+			invalidChallengeErr := domain.NewUnauthenticatedError("invalid or expired challenge token")
+			ctx.JSON(invalidChallengeErr.Status(), invalidChallengeErr)
+			return
+		}
+		user.TokenService = domain.NewTokenService()
+
+		twoFactor, err := repository.NewTwoFactorRepository().GetTwoFactor(db, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if twoFactor.EnabledAt == nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("two-factor authentication is not enabled"))
+			return
+		}
+
+		valid, err := verifySecondFactor(db, user.Id, twoFactor, body.Code)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if !valid {
+			recorder.Record(audit.FromRequest(ctx, domain.AuditEventLoginFailed, user.Id, map[string]any{
+				"method": "2fa",
+				"reason": "invalid code",
+			}))
+
+			if err := tracker.RecordFailure(ctx, key, loginguard.EmailPolicy); err != nil {
+				ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+				panic(err)
+			}
+
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("invalid code"))
+			return
+		}
+
+		if err := tracker.Reset(ctx, key); err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		token, refreshToken, err := issueSession(ctx, db, user)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventLoginSucceeded, user.Id, map[string]any{
+			"method":    "2fa",
+			"sessionId": user.SessionId,
+		}))
+
+		res := &userResponse{
+			Email:        user.Email,
+			Name:         user.Name,
+			AccessToken:  token,
+			RefreshToken: refreshToken,
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("User Logged Successfully", res))
+	}
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code. Both can be used only once.
+func verifySecondFactor(db *sql.DB, userId uuid.UUID, twoFactor *domain.TwoFactor, code string) (bool, error) {
+	twoFactorRepository := repository.NewTwoFactorRepository()
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(*twoFactor.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		return twoFactorRepository.UseTimeStep(db, userId, step)
+	}
+
+	return twoFactorRepository.UseRecoveryCode(db, userId, domain.HashRecoveryCode(code))
+}
@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"cats-social/internal/audit"
+	"cats-social/internal/blobstore"
+	"cats-social/internal/domain"
+	"cats-social/internal/imaging"
+	"cats-social/internal/mail"
+	"cats-social/internal/passwordpolicy"
+	"cats-social/internal/repository"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HandleGetProfile(db *sql.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		profile, err := repository.NewUserPg().GetById(db, user.Id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("user is not found"))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", domain.NewProfileResponse(profile)))
+	}
+}
+
+func HandleUpdateProfile(db *sql.DB, mailer mail.Sender, recorder audit.Recorder, passwordChecker *passwordpolicy.Checker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		var body domain.UpdateProfileRequest
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+			return
+		}
+
+		userRepository := repository.NewUserPg()
+
+		profile, err := userRepository.GetById(db, user.Id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("user is not found"))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		profile.TokenService = domain.NewTokenService()
+
+		emailChanged := body.Email != nil && *body.Email != profile.Email
+		passwordChanged := body.Password != nil
+
+		if emailChanged || passwordChanged {
+			if len(body.CurrentPassword) < 1 {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("currentPassword is required to change email or password"))
+				return
+			}
+			if !profile.ComparePassword(body.CurrentPassword) {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("current password is wrong"))
+				return
+			}
+		}
+
+		if body.Name != nil {
+			if len(*body.Name) < 5 || len(*body.Name) > 50 {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("minimum name is 5 length and maximum length is 50"))
+				return
+			}
+			profile.Name = *body.Name
+		}
+
+		if emailChanged {
+			if !validEmail(*body.Email) {
+				ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("invalid email format"))
+				return
+			}
+			if err := CheckDuplicateEmail(*body.Email, db); err != nil {
+				ctx.JSON(http.StatusConflict, domain.NewConflictError(err.Error()))
+				return
+			}
+			profile.Email = *body.Email
+			// the new address has to be verified again
+			profile.EmailVerifiedAt = nil
+		}
+
+		if passwordChanged {
+			if err := checkPassword(ctx, passwordChecker, *body.Password, profile.Email, profile.Name); err != nil {
+				ctx.JSON(err.Status(), err)
+				return
+			}
+			profile.Password = *body.Password
+			if err := profile.HashPassword(); err != nil {
+				ctx.JSON(err.Status(), err)
+				return
+			}
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		defer tx.Rollback()
+
+		err = userRepository.UpdateProfile(ctx, tx, profile)
+		if errors.Is(err, repository.ErrEmailTaken) {
+			ctx.JSON(http.StatusConflict, domain.NewConflictError(err.Error()))
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		if passwordChanged {
+			err = userRepository.UpdatePassword(ctx, tx, profile.Id, profile.Password)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+				panic(err)
+			}
+
+			// keep the device that changed the password signed in
+			err = repository.NewSessionPg().RevokeOtherUserSessions(ctx, tx, profile.Id, user.SessionId)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+				panic(err)
+			}
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		if emailChanged {
+			recorder.Record(audit.FromRequest(ctx, domain.AuditEventEmailChanged, profile.Id, map[string]any{
+				"from": user.Email,
+				"to":   profile.Email,
+			}))
+		}
+		if passwordChanged {
+			recorder.Record(audit.FromRequest(ctx, domain.AuditEventPasswordChanged, profile.Id, map[string]any{
+				"via": "profile",
+			}))
+		}
+
+		if emailChanged {
+			err = sendVerificationEmail(ctx, db, mailer, profile)
+			if err != nil {
+				log.Printf("failed to send verification email: %s", err)
+			}
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", domain.NewProfileResponse(profile)))
+	}
+}
+
+func HandleDeleteProfile(db *sql.DB, blobStore blobstore.BlobStore, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		catRepository := repository.NewCatRepository()
+
+		// the files of the cats go once the account is gone, a cat added
+		// meanwhile only leaves its files behind
+		catIds, err := catRepository.GetCatIdsByOwnerId(db, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		images, err := repository.NewCatImageRepository().GetCatImagesByCatIds(db, catIds)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		defer tx.Rollback()
+
+		err = catRepository.DeleteCatsByOwnerId(ctx, tx, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = repository.NewCatMatchRepository().WithdrawWaitingCatMatchesByUserID(ctx, tx, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = repository.NewCatLikeRepository().DeleteCatLikesByUserID(ctx, tx, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = repository.NewSessionPg().RevokeUserSessions(ctx, tx, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		// the email is free again, the provider accounts have to be too
+		err = repository.NewIdentityRepository().DeleteUserIdentities(ctx, tx, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = repository.NewUserPg().SoftDeleteUser(ctx, tx, user.Id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		imaging.DeleteImageBlobs(blobStore, images)
+
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventAccountDeleted, user.Id, map[string]any{
+			"deletedCats": len(catIds),
+		}))
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "success delete user"})
+	}
+}
+
+func HandleGetSecurityEvents(db *sql.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+		if err != nil || limit < 1 || limit > 100 {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("limit should be between 1 and 100"))
+			return
+		}
+
+		offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+		if err != nil || offset < 0 {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("offset should be a positive number"))
+			return
+		}
+
+		events, err := repository.NewAuditEventRepository().GetAuditEventsByActorId(db, user.Id, limit, offset)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("success", events))
+	}
+}
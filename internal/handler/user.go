@@ -1,23 +1,36 @@
 package handler
 
 import (
+	"cats-social/internal/audit"
 	"cats-social/internal/domain"
+	"cats-social/internal/loginguard"
+	"cats-social/internal/mail"
+	"cats-social/internal/passwordpolicy"
 	"cats-social/internal/repository"
+	"context"
 	"database/sql"
 	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type userResponse struct {
-	Email       string `json:"email" binding:"required,email"`
-	Name        string `json:"name" binding:"required"`
-	AccessToken string `json:"accessToken"`
+	Email        string `json:"email" binding:"required,email"`
+	Name         string `json:"name" binding:"required"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
 }
 
-func HandleNewUser(db *sql.DB) gin.HandlerFunc {
+func HandleNewUser(db *sql.DB, mailer mail.Sender, passwordChecker *passwordpolicy.Checker) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		userBody := domain.NewUser()
 
@@ -35,32 +48,46 @@ func HandleNewUser(db *sql.DB) gin.HandlerFunc {
 			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
 			return
 		}
+		if err := checkPassword(ctx, passwordChecker, userBody.Password, userBody.Email, userBody.Name); err != nil {
+			ctx.JSON(err.Status(), err)
+			return
+		}
 
 		userBody.HashPassword()
 
 		err = repository.NewUserPg().CreateNewUser(db, userBody)
+		if errors.Is(err, repository.ErrEmailTaken) {
+			ctx.JSON(http.StatusConflict, domain.NewConflictError(err.Error()))
+			return
+		}
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("SOMETHING WENT WRONG"))
 			panic(err)
 		}
 
-		token, err := userBody.GenerateToken()
+		err = sendVerificationEmail(ctx, db, mailer, userBody)
+		if err != nil {
+			log.Printf("failed to send verification email: %s", err)
+		}
+
+		token, refreshToken, err := issueSession(ctx, db, userBody)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, "something went wrong")
 			panic(err)
 		}
 
 		res := &userResponse{
-			Email:       userBody.Email,
-			Name:        userBody.Name,
-			AccessToken: token,
+			Email:        userBody.Email,
+			Name:         userBody.Name,
+			AccessToken:  token,
+			RefreshToken: refreshToken,
 		}
 
 		ctx.JSON(http.StatusCreated, domain.NewStatusCreated("User registered successfully", res))
 	}
 }
 
-func HandleLogin(db *sql.DB) gin.HandlerFunc {
+func HandleLogin(db *sql.DB, tracker loginguard.Tracker, recorder audit.Recorder) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		userBody := domain.NewUser()
 
@@ -75,33 +102,257 @@ func HandleLogin(db *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		emailKey := loginguard.EmailKey(userBody.Email)
+		ipKey := loginguard.IPKey(ctx.ClientIP())
+
+		for _, key := range []string{emailKey, ipKey} {
+			retryAfter, err := tracker.Locked(ctx, key)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+				panic(err)
+			}
+			if retryAfter > 0 {
+				recorder.Record(audit.FromRequest(ctx, domain.AuditEventLoginFailed, uuid.Nil, map[string]any{
+					"method": "password",
+					"email":  userBody.Email,
+					"reason": "locked",
+				}))
+
+				ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				tooManyAttemptsErr := domain.NewTooManyRequestsError("too many login attempts, try again later")
+				ctx.JSON(tooManyAttemptsErr.Status(), tooManyAttemptsErr)
+				return
+			}
+		}
+
+		invalidCredentialsErr := domain.NewBadRequest("invalid email or password")
+
 		user, err := repository.NewUserPg().GetByEmail(db, userBody.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		// unknown emails still pay for a bcrypt comparison so that response
+		// times do not reveal which emails are registered
+		isValidPassword := false
+		if user != nil {
+			isValidPassword = user.ComparePassword(userBody.Password)
+		} else {
+			bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(userBody.Password))
+		}
+
+		if !isValidPassword {
+			actorId := uuid.Nil
+			if user != nil {
+				actorId = user.Id
+			}
+			recorder.Record(audit.FromRequest(ctx, domain.AuditEventLoginFailed, actorId, map[string]any{
+				"method": "password",
+				"email":  userBody.Email,
+				"reason": "invalid credentials",
+			}))
+
+			for key, policy := range map[string]loginguard.Policy{emailKey: loginguard.EmailPolicy, ipKey: loginguard.IPPolicy} {
+				if err := tracker.RecordFailure(ctx, key, policy); err != nil {
+					ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+					panic(err)
+				}
+			}
+
+			ctx.JSON(invalidCredentialsErr.Status(), invalidCredentialsErr)
+			return
+		}
+		if err := tracker.Reset(ctx, emailKey); err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if user.IsDisabled() {
+			recorder.Record(audit.FromRequest(ctx, domain.AuditEventLoginFailed, user.Id, map[string]any{
+				"method": "password",
+				"email":  userBody.Email,
+				"reason": "account disabled",
+			}))
+
+			ctx.JSON(http.StatusForbidden, domain.NewUnauthorizedError("account has been disabled"))
+			return
+		}
+		user.TokenService = domain.NewTokenService()
+
+		completeLogin(ctx, db, recorder, user, "password")
+	}
+}
+
+// completeLogin answers a successful first-factor login: with a two-factor
+// challenge when the user has it enabled, with a new session otherwise.
+func completeLogin(ctx *gin.Context, db *sql.DB, recorder audit.Recorder, user *domain.User, method string) {
+	if user.IsTwoFactorEnabled() {
+		challengeToken, err := domain.NewTwoFactorChallenge(user.Id)
 		if err != nil {
-			ctx.JSON(http.StatusNotFound, domain.NewNotFoundError("email has been used"))
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		res := &domain.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("Two-factor authentication required", res))
+		return
+	}
+
+	token, refreshToken, err := issueSession(ctx, db, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError(err.Error()))
+		panic(err)
+	}
+
+	recorder.Record(audit.FromRequest(ctx, domain.AuditEventLoginSucceeded, user.Id, map[string]any{
+		"method":    method,
+		"sessionId": user.SessionId,
+	}))
+
+	res := &userResponse{
+		Email:        user.Email,
+		Name:         user.Name,
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+	}
+
+	ctx.JSON(http.StatusOK, domain.NewStatusOk("User Logged Successfully", res))
+}
+
+func HandleRefreshToken(db *sql.DB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		invalidRefreshTokenErr := domain.NewUnauthenticatedError("invalid refresh token")
+
+		var body domain.RefreshTokenRequest
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest(err.Error()))
+			return
+		}
+		if len(body.RefreshToken) < 1 {
+			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("refreshToken not be empty"))
 			return
 		}
-		userBody.Id = user.Id
 
-		isValidPassword := user.ComparePassword(userBody.Password)
-		if !isValidPassword {
-			ctx.JSON(http.StatusBadRequest, domain.NewBadRequest("invalid email or password"))
+		sessionRepository := repository.NewSessionPg()
+
+		oldHash := domain.HashOpaqueToken(body.RefreshToken)
+		session, err := sessionRepository.GetByRefreshTokenHash(db, oldHash)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(invalidRefreshTokenErr.Status(), invalidRefreshTokenErr)
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if !session.IsActive() {
+			ctx.JSON(invalidRefreshTokenErr.Status(), invalidRefreshTokenErr)
 			return
 		}
 
-		token, err := userBody.GenerateToken()
+		refreshToken, newHash, err := domain.NewOpaqueToken()
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError(err.Error()))
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
 			panic(err)
 		}
 
-		res := &userResponse{
-			Email:       user.Email,
-			Name:        user.Name,
-			AccessToken: token,
+		rotated, err := sessionRepository.RotateRefreshToken(db, session.ID, oldHash, newHash, time.Now().Add(domain.SessionTTL))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+		if !rotated {
+			ctx.JSON(invalidRefreshTokenErr.Status(), invalidRefreshTokenErr)
+			return
+		}
+
+		user, err := repository.NewUserPg().GetById(db, session.UserID)
+		if err != nil || user.IsDisabled() {
+			ctx.JSON(invalidRefreshTokenErr.Status(), invalidRefreshTokenErr)
+			return
+		}
+		user.TokenService = domain.NewTokenService()
+		user.SessionId = session.ID
+
+		token, err := user.GenerateToken()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
+		}
+
+		res := &domain.TokenResponse{
+			AccessToken:  token,
+			RefreshToken: refreshToken,
+		}
+
+		ctx.JSON(http.StatusOK, domain.NewStatusOk("Token refreshed successfully", res))
+	}
+}
+
+func HandleLogout(db *sql.DB, recorder audit.Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userReq, _ := ctx.Get("userData")
+		user := userReq.(*domain.User)
+
+		err := repository.NewSessionPg().RevokeSession(db, user.SessionId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, domain.NewInternalServerError("something went wrong"))
+			panic(err)
 		}
 
-		ctx.JSON(http.StatusOK, domain.NewStatusOk("User Logged Successfully", res))
+		recorder.Record(audit.FromRequest(ctx, domain.AuditEventLogout, user.Id, map[string]any{
+			"sessionId": user.SessionId,
+		}))
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "User logged out successfully"})
+	}
+}
+
+// issueSession starts a new server-side session for the user and returns an
+// access token bound to it together with the session's refresh token.
+func issueSession(ctx *gin.Context, db *sql.DB, user *domain.User) (string, string, error) {
+	refreshToken, hash, err := domain.NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	session := domain.NewSession(user.Id, domain.TruncateUserAgent(ctx.Request.UserAgent()), ctx.ClientIP())
+	session.RefreshTokenHash = hash
+
+	err = repository.NewSessionPg().CreateSession(db, session)
+	if err != nil {
+		return "", "", err
+	}
+	user.SessionId = session.ID
+
+	token, err := user.GenerateToken()
+	if err != nil {
+		return "", "", err
 	}
+
+	return token, refreshToken, nil
+}
+
+var (
+	dummyPasswordHashOnce sync.Once
+	dummyPasswordHashVal  []byte
+)
+
+// dummyPasswordHash is hashed with the configured cost so comparing against it
+// takes as long as comparing against a real password.
+func dummyPasswordHash() []byte {
+	dummyPasswordHashOnce.Do(func() {
+		dummy := domain.NewUser()
+		dummy.Password = uuid.NewString()
+		dummy.HashPassword()
+		dummyPasswordHashVal = []byte(dummy.Password)
+	})
+
+	return dummyPasswordHashVal
 }
 
 func validateLoginUser(user domain.User, db *sql.DB) error {
@@ -127,15 +378,16 @@ func validateLoginUser(user domain.User, db *sql.DB) error {
 	return nil
 }
 
+// CheckDuplicateEmail ignores deleted accounts, their email is free again.
 func CheckDuplicateEmail(email string, db *sql.DB) error {
 	var count int
-	row := db.QueryRow("SELECT COUNT(*) FROM users WHERE email = $1", email)
+	row := db.QueryRow("SELECT COUNT(*) FROM users WHERE email = $1 AND deleted_at IS NULL", email)
 	if err := row.Scan(&count); err != nil {
 		return err
 	}
 
 	if count > 0 {
-		return errors.New("email has been used")
+		return repository.ErrEmailTaken
 	}
 
 	return nil
@@ -158,20 +410,32 @@ func ValidateUserRequest(userBody *domain.User, db *sql.DB) error {
 	}
 
 	if len(userBody.Name) < 5 || len(userBody.Name) > 50 {
-		err := errors.New("minimum password is 5 length and maximum length is 50")
+		err := errors.New("minimum name is 5 length and maximum length is 50")
 		return err
 	}
 
-	if len(userBody.Password) < 1 {
-		err := errors.New("password not be empty")
-		return err
+	return nil
+}
+
+// checkPassword validates a new password against the password policy and
+// the breached password list. personalInfo is what the password must not
+// contain, usually the user's email and name.
+func checkPassword(ctx context.Context, checker *passwordpolicy.Checker, password string, personalInfo ...string) domain.MessageErr {
+	violations, err := checker.Check(ctx, password, personalInfo...)
+	if err != nil {
+		log.Printf("failed to check password: %s", err)
+		return domain.NewInternalServerError("something went wrong")
+	}
+	if len(violations) == 0 {
+		return nil
 	}
 
-	if len(userBody.Password) < 5 || len(userBody.Password) > 15 {
-		err := errors.New("minimum password is 5 length and maximum length is 15")
-		return err
+	ruleViolations := make([]domain.RuleViolation, 0, len(violations))
+	for _, violation := range violations {
+		ruleViolations = append(ruleViolations, domain.RuleViolation{Rule: violation.Rule, Message: violation.Message})
 	}
-	return nil
+
+	return domain.NewPasswordPolicyError(ruleViolations)
 }
 
 func validEmail(email string) bool {
@@ -0,0 +1,257 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+const (
+	tagOrientation = 0x0112
+	tagGPSInfo     = 0x8825
+)
+
+var (
+	jpegExifHeader = []byte("Exif\x00\x00")
+	pngSignature   = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// StripGPS returns data with the GPS block of its EXIF metadata blanked out.
+// The rest of the metadata, the orientation in particular, is kept. Data
+// that is not a JPEG or PNG, or carries no EXIF, is returned unchanged.
+func StripGPS(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		return stripJPEGGPS(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNGGPS(data)
+	default:
+		return data
+	}
+}
+
+// Orientation reads the EXIF orientation, 1 to 8, of a JPEG or PNG. It is 1
+// when the image has none.
+func Orientation(data []byte) int {
+	var tiff []byte
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		tiff, _ = jpegExif(data)
+	case bytes.HasPrefix(data, pngSignature):
+		tiff, _ = pngExif(data)
+	}
+	if tiff == nil {
+		return 1
+	}
+
+	ifd, ok := parseTIFF(tiff)
+	if !ok {
+		return 1
+	}
+	entry, ok := ifd.find(tagOrientation)
+	if !ok || entry.typ != 3 {
+		return 1
+	}
+
+	orientation := int(ifd.order.Uint16(tiff[entry.at+8:]))
+	if orientation < 1 || orientation > 8 {
+		return 1
+	}
+
+	return orientation
+}
+
+func stripJPEGGPS(data []byte) []byte {
+	tiff, start := jpegExif(data)
+	if tiff == nil {
+		return data
+	}
+
+	out := bytes.Clone(data)
+	blankGPS(out[start : start+len(tiff)])
+
+	return out
+}
+
+func stripPNGGPS(data []byte) []byte {
+	tiff, start := pngExif(data)
+	if tiff == nil {
+		return data
+	}
+
+	out := bytes.Clone(data)
+	chunk := out[start : start+len(tiff)]
+	if !blankGPS(chunk) {
+		return data
+	}
+
+	// the chunk CRC covers the chunk type and its data
+	crc := crc32.ChecksumIEEE(out[start-4 : start+len(tiff)])
+	binary.BigEndian.PutUint32(out[start+len(tiff):], crc)
+
+	return out
+}
+
+// jpegExif finds the TIFF structure inside the APP1 Exif segment and returns
+// it with its offset in data.
+func jpegExif(data []byte) ([]byte, int) {
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xff {
+			return nil, 0
+		}
+		marker := data[i+1]
+		// start of scan, no metadata follows
+		if marker == 0xda || marker == 0xd9 {
+			return nil, 0
+		}
+
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return nil, 0
+		}
+		segment := data[i+4 : i+2+size]
+
+		if marker == 0xe1 && bytes.HasPrefix(segment, jpegExifHeader) {
+			return segment[len(jpegExifHeader):], i + 4 + len(jpegExifHeader)
+		}
+
+		i += 2 + size
+	}
+
+	return nil, 0
+}
+
+// pngExif finds the eXIf chunk and returns its data with its offset in data.
+func pngExif(data []byte) ([]byte, int) {
+	i := len(pngSignature)
+	for i+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[i:]))
+		typ := string(data[i+4 : i+8])
+		if size < 0 || i+12+size > len(data) {
+			return nil, 0
+		}
+
+		if typ == "eXIf" {
+			return data[i+8 : i+8+size], i + 8
+		}
+		if typ == "IDAT" || typ == "IEND" {
+			return nil, 0
+		}
+
+		i += 12 + size
+	}
+
+	return nil, 0
+}
+
+type tiffEntry struct {
+	tag uint16
+	typ uint16
+	// at is the offset of the 12 byte entry within the TIFF structure
+	at int
+}
+
+type tiffIFD struct {
+	order   binary.ByteOrder
+	entries []tiffEntry
+}
+
+func (d tiffIFD) find(tag uint16) (tiffEntry, bool) {
+	for _, entry := range d.entries {
+		if entry.tag == tag {
+			return entry, true
+		}
+	}
+
+	return tiffEntry{}, false
+}
+
+// parseTIFF reads the header and the first IFD of a TIFF structure.
+func parseTIFF(tiff []byte) (tiffIFD, bool) {
+	if len(tiff) < 8 {
+		return tiffIFD{}, false
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return tiffIFD{}, false
+	}
+
+	ifd, ok := readIFD(tiff, order, int(order.Uint32(tiff[4:])))
+	return ifd, ok
+}
+
+func readIFD(tiff []byte, order binary.ByteOrder, offset int) (tiffIFD, bool) {
+	if offset < 8 || offset+2 > len(tiff) {
+		return tiffIFD{}, false
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	if offset+2+count*12 > len(tiff) {
+		return tiffIFD{}, false
+	}
+
+	ifd := tiffIFD{order: order}
+	for n := range count {
+		at := offset + 2 + n*12
+		ifd.entries = append(ifd.entries, tiffEntry{
+			tag: order.Uint16(tiff[at:]),
+			typ: order.Uint16(tiff[at+2:]),
+			at:  at,
+		})
+	}
+
+	return ifd, true
+}
+
+// tiffTypeSizes is the size in bytes of one value of each TIFF field type.
+var tiffTypeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// blankGPS zeroes the GPS IFD, including the values it points to, and
+// leaves an empty IFD behind so the pointer to it stays valid. It reports
+// whether anything was changed.
+func blankGPS(tiff []byte) bool {
+	ifd, ok := parseTIFF(tiff)
+	if !ok {
+		return false
+	}
+	pointer, ok := ifd.find(tagGPSInfo)
+	if !ok {
+		return false
+	}
+
+	order := ifd.order
+	gps, ok := readIFD(tiff, order, int(order.Uint32(tiff[pointer.at+8:])))
+	if !ok {
+		return false
+	}
+
+	for _, entry := range gps.entries {
+		count := int(order.Uint32(tiff[entry.at+4:]))
+		size := tiffTypeSizes[entry.typ] * count
+		if size > 4 {
+			valueAt := int(order.Uint32(tiff[entry.at+8:]))
+			if valueAt >= 0 && size <= len(tiff) && valueAt <= len(tiff)-size {
+				clear(tiff[valueAt : valueAt+size])
+			}
+		}
+		clear(tiff[entry.at : entry.at+12])
+	}
+
+	offset := int(order.Uint32(tiff[pointer.at+8:]))
+	order.PutUint16(tiff[offset:], 0)
+	// the next IFD offset follows the (now empty) entry list
+	if offset+6 <= len(tiff) {
+		order.PutUint32(tiff[offset+2:], 0)
+	}
+
+	return true
+}
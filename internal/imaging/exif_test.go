@@ -0,0 +1,193 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// gpsLatitude is the GPSLatitude value of testTIFF, 48/1 51/1 2980/100.
+var gpsLatitude = []byte{
+	48, 0, 0, 0, 1, 0, 0, 0,
+	51, 0, 0, 0, 1, 0, 0, 0,
+	0xa4, 0x0b, 0, 0, 100, 0, 0, 0,
+}
+
+// testTIFF builds little endian EXIF data with an orientation of 6 and a GPS
+// IFD holding a latitude reference and a latitude.
+func testTIFF() []byte {
+	le := binary.LittleEndian
+	tiff := make([]byte, 92)
+	copy(tiff, "II*\x00")
+	le.PutUint32(tiff[4:], 8)
+
+	// IFD0 at 8: orientation and the pointer to the GPS IFD at 38
+	le.PutUint16(tiff[8:], 2)
+	putEntry(tiff[10:], tagOrientation, 3, 1, 6)
+	putEntry(tiff[22:], tagGPSInfo, 4, 1, 38)
+
+	// GPS IFD at 38: GPSLatitudeRef "N" inline, GPSLatitude stored at 68
+	le.PutUint16(tiff[38:], 2)
+	putEntry(tiff[40:], 1, 2, 2, uint32('N'))
+	putEntry(tiff[52:], 2, 5, 3, 68)
+	copy(tiff[68:], gpsLatitude)
+
+	return tiff
+}
+
+func putEntry(b []byte, tag uint16, typ uint16, count uint32, value uint32) {
+	le := binary.LittleEndian
+	le.PutUint16(b, tag)
+	le.PutUint16(b[2:], typ)
+	le.PutUint32(b[4:], count)
+	le.PutUint32(b[8:], value)
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := range 8 {
+		for x := range 16 {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 32), 128, 255})
+		}
+	}
+
+	return img
+}
+
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// withAPP1 inserts an APP1 segment of the given declared length right after
+// the start of image marker.
+func withAPP1(data []byte, payload []byte, length int) []byte {
+	segment := []byte{0xff, 0xe1, byte(length >> 8), byte(length)}
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func exifJPEG(t *testing.T, tiff []byte) []byte {
+	t.Helper()
+
+	payload := append(append([]byte{}, jpegExifHeader...), tiff...)
+	return withAPP1(testJPEG(t), payload, len(payload)+2)
+}
+
+func exifPNG(t *testing.T, tiff []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// the eXIf chunk goes right after IHDR, the signature and IHDR take 33
+	// bytes
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(tiff)))
+	chunk = append(chunk, "eXIf"...)
+	chunk = append(chunk, tiff...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	out := append([]byte{}, data[:33]...)
+	out = append(out, chunk...)
+	return append(out, data[33:]...)
+}
+
+func TestStripGPS(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		decode  func(data []byte) (image.Image, error)
+		changed bool
+	}{
+		{
+			name:    "jpeg with gps",
+			data:    func(t *testing.T) []byte { return exifJPEG(t, testTIFF()) },
+			decode:  func(data []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(data)) },
+			changed: true,
+		},
+		{
+			name:    "png with gps",
+			data:    func(t *testing.T) []byte { return exifPNG(t, testTIFF()) },
+			decode:  func(data []byte) (image.Image, error) { return png.Decode(bytes.NewReader(data)) },
+			changed: true,
+		},
+		{
+			name:   "jpeg without exif",
+			data:   testJPEG,
+			decode: func(data []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(data)) },
+		},
+		{
+			name: "jpeg with exif but no gps",
+			data: func(t *testing.T) []byte {
+				tiff := testTIFF()[:38]
+				binary.LittleEndian.PutUint16(tiff[8:], 1)
+				return exifJPEG(t, tiff)
+			},
+			decode: func(data []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(data)) },
+		},
+		{
+			name: "jpeg with a truncated gps ifd",
+			// the APP1 segment ends inside the GPS IFD it points to
+			data:   func(t *testing.T) []byte { return exifJPEG(t, testTIFF()[:44]) },
+			decode: func(data []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(data)) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data(t)
+			original := bytes.Clone(data)
+
+			out := StripGPS(data)
+
+			if !bytes.Equal(data, original) {
+				t.Fatal("StripGPS modified its input")
+			}
+			if len(out) != len(data) {
+				t.Fatalf("output is %d bytes, want %d", len(out), len(data))
+			}
+			if changed := !bytes.Equal(out, data); changed != tt.changed {
+				t.Fatalf("output changed is %t, want %t", changed, tt.changed)
+			}
+			if tt.changed && bytes.Contains(out, gpsLatitude) {
+				t.Error("output still holds the latitude")
+			}
+			if _, err := tt.decode(out); err != nil {
+				t.Errorf("output does not decode: %s", err)
+			}
+			if tt.changed {
+				if got := Orientation(out); got != 6 {
+					t.Errorf("orientation is %d after stripping, want 6", got)
+				}
+			}
+		})
+	}
+}
+
+func TestStripGPSTruncatedSegment(t *testing.T) {
+	// the APP1 segment claims more bytes than the file holds
+	payload := append(append([]byte{}, jpegExifHeader...), testTIFF()...)
+	data := withAPP1(testJPEG(t)[:2], payload, len(payload)+200)
+
+	out := StripGPS(data)
+	if !bytes.Equal(out, data) {
+		t.Error("a truncated APP1 segment was changed")
+	}
+}
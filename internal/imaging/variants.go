@@ -0,0 +1,118 @@
+// Package imaging makes the resized variants of uploaded cat images and
+// removes location data from them.
+package imaging
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// Spec describes one variant. With Crop the image is cut to the aspect
+// ratio of Width x Height around its centre, otherwise it is scaled to fit
+// inside. Images are never enlarged.
+type Spec struct {
+	Name    string
+	Width   int
+	Height  int
+	Crop    bool
+	Quality int
+}
+
+// Specs are the variants made of every uploaded image.
+var Specs = []Spec{
+	{Name: "thumbnail", Width: 320, Height: 320, Crop: true, Quality: 75},
+	{Name: "medium", Width: 960, Height: 960, Quality: 80},
+}
+
+// All variants are JPEG: the standard library has no WebP encoder.
+const (
+	variantContentType = "image/jpeg"
+	variantExtension   = "jpg"
+)
+
+// Variant is an encoded variant.
+type Variant struct {
+	Name        string
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Generate decodes an image and makes every variant of Specs from it. The
+// EXIF orientation is applied, and since the variants are re-encoded they
+// carry no metadata at all. Animated GIFs use their first frame.
+func Generate(data []byte) ([]Variant, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	flat := flatten(src)
+	orientation := Orientation(data)
+
+	variants := make([]Variant, 0, len(Specs))
+	for _, spec := range Specs {
+		img := scale(flat, orientation, spec)
+
+		var buf bytes.Buffer
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: spec.Quality})
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, Variant{
+			Name:        spec.Name,
+			Data:        buf.Bytes(),
+			ContentType: variantContentType,
+			Width:       img.Bounds().Dx(),
+			Height:      img.Bounds().Dy(),
+		})
+	}
+
+	return variants, nil
+}
+
+// scale crops and resizes src as spec says and turns it upright. Sizes are
+// worked out on the upright image, the pixels are resized before turning
+// since there are fewer of them by then.
+func scale(src *image.RGBA, orientation int, spec Spec) *image.RGBA {
+	rotated := orientation >= 5
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if rotated {
+		w, h = h, w
+	}
+
+	// region of the upright image to keep
+	cw, ch := w, h
+	if spec.Crop {
+		if w*spec.Height > h*spec.Width {
+			cw = h * spec.Width / spec.Height
+		} else {
+			ch = w * spec.Height / spec.Width
+		}
+	}
+
+	// size of the variant, fitting inside the spec
+	dw, dh := cw, ch
+	if dw > spec.Width {
+		dw, dh = spec.Width, dh*spec.Width/dw
+	}
+	if dh > spec.Height {
+		dw, dh = dw*spec.Height/dh, spec.Height
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	// back to stored orientation, a centred crop stays centred
+	if rotated {
+		cw, ch, dw, dh = ch, cw, dh, dw
+	}
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	rect := image.Rect((sw-cw)/2, (sh-ch)/2, (sw-cw)/2+cw, (sh-ch)/2+ch)
+
+	return orient(resize(src, rect, dw, dh), orientation)
+}
@@ -0,0 +1,257 @@
+package imaging
+
+import (
+	"cats-social/internal/blobstore"
+	"cats-social/internal/domain"
+	"cats-social/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	pollInterval   = 30 * time.Second
+	processTimeout = 2 * time.Minute
+	maxAttempts    = 5
+	retryDelay     = 30 * time.Second
+
+	// writeTimeout bounds the short transactions around an attempt
+	writeTimeout = 10 * time.Second
+)
+
+// Worker makes the variants of uploaded images in the background. The work
+// queue is the cat_images table itself, so pending images survive restarts
+// and several instances can run a worker each.
+//
+// Processing is idempotent: variants are stored under keys derived from the
+// image, so a retry after a failure overwrites what an earlier attempt left.
+// An image is claimed in a short transaction and its variants are made
+// without holding a lock; the outcome is saved afterwards.
+type Worker struct {
+	db                 *sql.DB
+	catImageRepository repository.CatImageRepository
+	blobStore          blobstore.BlobStore
+	wake               chan struct{}
+}
+
+func NewWorker(db *sql.DB, catImageRepository repository.CatImageRepository, blobStore blobstore.BlobStore) *Worker {
+	return &Worker{
+		db:                 db,
+		catImageRepository: catImageRepository,
+		blobStore:          blobStore,
+		wake:               make(chan struct{}, 1),
+	}
+}
+
+// Start runs the worker until the process exits.
+func (w *Worker) Start() {
+	go w.run()
+}
+
+// Notify tells the worker new images are waiting, so it does not wait for
+// the next poll. It never blocks.
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Worker) run() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.processNext() {
+		}
+
+		select {
+		case <-w.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext handles one due image and reports whether there was one. No
+// transaction is open and no row locked while the variants are made.
+func (w *Worker) processNext() bool {
+	image, err := w.claim()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("image variants: failed to claim image: %s", err)
+		}
+		return false
+	}
+
+	// a worker died on the last attempt, do not try again
+	if image.VariantsAttempts > maxAttempts {
+		w.recordFailure(image, errors.New("gave up after too many attempts"))
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), processTimeout)
+	defer cancel()
+
+	variants, err := w.makeVariants(ctx, image)
+	if err != nil {
+		w.recordFailure(image, err)
+		return true
+	}
+
+	w.save(ctx, image, variants)
+	return true
+}
+
+// claim takes the next due image and counts the attempt in a transaction of
+// its own. It stays claimed until processTimeout passed.
+func (w *Worker) claim() (*domain.CatImage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	image, err := w.catImageRepository.ClaimPendingCatImage(ctx, tx, time.Now().Add(processTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+func (w *Worker) save(ctx context.Context, image *domain.CatImage, variants map[string]domain.CatImageVariant) {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("image variants: failed to start transaction: %s", err)
+		return
+	}
+	defer tx.Rollback()
+
+	found, err := w.catImageRepository.SaveCatImageVariants(ctx, tx, image.ID, variants)
+	if err != nil {
+		log.Printf("image variants: failed to save variants of image %s: %s", image.ID, err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("image variants: failed to save variants of image %s: %s", image.ID, err)
+		return
+	}
+
+	// the image or its cat was deleted meanwhile, its variants were made too
+	// late for the delete to remove them
+	if !found {
+		for _, key := range VariantKeys(image.StorageKey) {
+			if err := w.blobStore.Delete(ctx, key); err != nil {
+				log.Printf("image variants: failed to delete blob %s: %s", key, err)
+			}
+		}
+	}
+}
+
+// recordFailure schedules the next attempt, or gives up after maxAttempts.
+// It does not use the context of the attempt, which may be what ran out.
+func (w *Worker) recordFailure(image *domain.CatImage, cause error) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	attempts := image.VariantsAttempts
+	status := domain.CatImageVariantsPending
+	if attempts >= maxAttempts {
+		status = domain.CatImageVariantsFailed
+	}
+	// back off 30s, 1m, 2m, 4m
+	nextAttemptAt := time.Now().Add(retryDelay << (min(attempts, maxAttempts) - 1))
+
+	log.Printf("image variants: attempt %d for image %s failed: %s", attempts, image.ID, cause)
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("image variants: failed to start transaction: %s", err)
+		return
+	}
+	defer tx.Rollback()
+
+	err = w.catImageRepository.FailCatImageVariants(ctx, tx, image.ID, status, attempts, nextAttemptAt, cause.Error())
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("image variants: failed to record failure of image %s: %s", image.ID, err)
+	}
+}
+
+func (w *Worker) makeVariants(ctx context.Context, image *domain.CatImage) (map[string]domain.CatImageVariant, error) {
+	data, err := w.blobStore.Get(ctx, image.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	generated, err := Generate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make(map[string]domain.CatImageVariant, len(generated))
+	for _, variant := range generated {
+		key := VariantKey(image.StorageKey, variant.Name)
+
+		err := w.blobStore.Put(ctx, key, variant.Data, variant.ContentType)
+		if err != nil {
+			return nil, err
+		}
+
+		variants[variant.Name] = domain.CatImageVariant{
+			Key:         key,
+			URL:         w.blobStore.URL(key),
+			ContentType: variant.ContentType,
+			Width:       variant.Width,
+			Height:      variant.Height,
+		}
+	}
+
+	return variants, nil
+}
+
+// VariantKey places variants next to their original:
+// cats/{catId}/{imageId}.png becomes cats/{catId}/{imageId}/{name}.jpg.
+func VariantKey(storageKey string, name string) string {
+	return strings.TrimSuffix(storageKey, path.Ext(storageKey)) + "/" + name + "." + variantExtension
+}
+
+// VariantKeys lists the keys every variant of an image is stored under,
+// whether or not it has been made yet.
+func VariantKeys(storageKey string) []string {
+	keys := make([]string, 0, len(Specs))
+	for _, spec := range Specs {
+		keys = append(keys, VariantKey(storageKey, spec.Name))
+	}
+
+	return keys
+}
+
+// DeleteImageBlobs deletes the originals of images and all their variants.
+// Failures are only logged: the rows are gone or hidden already, so nothing
+// points at a leftover file.
+func DeleteImageBlobs(blobStore blobstore.BlobStore, images []domain.CatImage) {
+	for _, image := range images {
+		for _, key := range append([]string{image.StorageKey}, VariantKeys(image.StorageKey)...) {
+			if err := blobStore.Delete(context.Background(), key); err != nil {
+				log.Printf("failed to delete blob %s: %s", key, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// StatusActive marks the single key new tokens are signed with.
+	StatusActive = "active"
+	// StatusVerify keys no longer sign but still verify tokens issued before
+	// a rotation.
+	StatusVerify = "verify"
+	// StatusRetired keys are neither accepted nor published.
+	StatusRetired = "retired"
+)
+
+const manifestFile = "keyring.json"
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type Key struct {
+	ID      string
+	Status  string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// Keyring holds the signing keys listed in the keyring.json manifest of a
+// directory, e.g.
+//
+//	{"keys": [
+//	  {"kid": "2024-06", "file": "2024-06.pem", "status": "active"},
+//	  {"kid": "2024-05", "file": "2024-05.pem", "status": "verify"}
+//	]}
+//
+// Key files are PKCS#8 PEM encoded RSA (RS256) or Ed25519 (EdDSA) private keys.
+type Keyring struct {
+	keys   map[string]*Key
+	active *Key
+}
+
+type manifest struct {
+	Keys []struct {
+		Kid    string `json:"kid"`
+		File   string `json:"file"`
+		Status string `json:"status"`
+	} `json:"keys"`
+}
+
+func Load(dir string) (*Keyring, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("read keyring manifest: %w", err)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("parse keyring manifest: %w", err)
+	}
+
+	kr := &Keyring{keys: map[string]*Key{}}
+	for _, entry := range m.Keys {
+		if entry.Kid == "" {
+			return nil, errors.New("keyring: key without kid")
+		}
+		if _, ok := kr.keys[entry.Kid]; ok {
+			return nil, fmt.Errorf("keyring: duplicate kid %q", entry.Kid)
+		}
+
+		switch entry.Status {
+		case StatusActive, StatusVerify:
+		case StatusRetired:
+			// retired keys are not even loaded
+			continue
+		default:
+			return nil, fmt.Errorf("keyring: key %q has unknown status %q", entry.Kid, entry.Status)
+		}
+
+		key, err := loadKey(filepath.Join(dir, entry.File))
+		if err != nil {
+			return nil, fmt.Errorf("keyring: key %q: %w", entry.Kid, err)
+		}
+		key.ID = entry.Kid
+		key.Status = entry.Status
+
+		if key.Status == StatusActive {
+			if kr.active != nil {
+				return nil, errors.New("keyring: more than one active key")
+			}
+			kr.active = key
+		}
+		kr.keys[key.ID] = key
+	}
+
+	if kr.active == nil {
+		return nil, errors.New("keyring: no active key")
+	}
+
+	return kr, nil
+}
+
+// LoadFromEnv loads the keyring from JWT_KEYS_DIR, defaulting to ./keys.
+func LoadFromEnv() (*Keyring, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		dir = "keys"
+	}
+
+	return Load(dir)
+}
+
+func loadKey(path string) (*Key, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return &Key{Method: jwt.SigningMethodRS256, Private: private, Public: &private.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{Method: jwt.SigningMethodEdDSA, Private: private, Public: private.Public()}, nil
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+}
+
+func (k *Keyring) Active() *Key {
+	return k.active
+}
+
+// Sign signs the claims with the active key and sets the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+
+	return token.SignedString(k.active.Private)
+}
+
+// Keyfunc resolves the verification key of a token from its kid header. It
+// only accepts the algorithm the key was loaded for.
+func (k *Keyring) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, ok := t.Header["kid"].(string)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnknownKey
+	}
+
+	return key.Public, nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public part of every non-retired key.
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	for _, key := range k.keys {
+		jwk := JWK{
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// SetDefault installs the process wide keyring used for issuing and
+// validating access tokens.
+func SetDefault(k *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultKeyring = k
+}
+
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultKeyring
+}
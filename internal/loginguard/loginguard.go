@@ -0,0 +1,78 @@
+package loginguard
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"time"
+)
+
+// Policy describes when a key gets locked. The first Threshold failures
+// within Window are free; every further failure locks the key for
+// BaseLockout doubled per extra failure, capped at MaxLockout.
+type Policy struct {
+	Threshold   int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+var (
+	EmailPolicy = Policy{
+		Threshold:   5,
+		Window:      time.Minute * 15,
+		BaseLockout: time.Second * 30,
+		MaxLockout:  time.Minute * 15,
+	}
+	// many users can share one address behind a NAT, so IPs get more room
+	IPPolicy = Policy{
+		Threshold:   20,
+		Window:      time.Minute * 15,
+		BaseLockout: time.Second * 30,
+		MaxLockout:  time.Minute * 15,
+	}
+)
+
+func (p Policy) lockoutFor(failures int) time.Duration {
+	if failures <= p.Threshold {
+		return 0
+	}
+
+	lockout := p.BaseLockout
+	for i := p.Threshold + 1; i < failures; i++ {
+		lockout *= 2
+		if lockout >= p.MaxLockout {
+			return p.MaxLockout
+		}
+	}
+
+	return lockout
+}
+
+// Tracker counts failed logins per key (an email or a client IP).
+type Tracker interface {
+	// Locked returns how long the key stays locked, or 0 when it is not.
+	Locked(ctx context.Context, key string) (time.Duration, error)
+	RecordFailure(ctx context.Context, key string, policy Policy) error
+	Reset(ctx context.Context, key string) error
+}
+
+func EmailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// NewTrackerFromEnv picks the store configured by LOGIN_GUARD_STORE, "memory"
+// (default) or "postgres". Use postgres when running more than one instance.
+func NewTrackerFromEnv(db *sql.DB) Tracker {
+	switch os.Getenv("LOGIN_GUARD_STORE") {
+	case "postgres":
+		return NewPostgresTracker(db)
+	default:
+		return NewMemoryTracker()
+	}
+}
@@ -0,0 +1,96 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type attempt struct {
+	failures      int
+	lastFailureAt time.Time
+	lockedUntil   time.Time
+	window        time.Duration
+}
+
+// evictEvery is the number of recorded failures between two sweeps of
+// stale keys.
+const evictEvery = 256
+
+type memoryTracker struct {
+	mu       sync.Mutex
+	attempts map[string]*attempt
+	writes   int
+}
+
+func NewMemoryTracker() Tracker {
+	return &memoryTracker{
+		attempts: map[string]*attempt{},
+	}
+}
+
+func (m *memoryTracker) Locked(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.attempts[key]
+	if !ok {
+		return 0, nil
+	}
+
+	now := time.Now()
+	if now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now), nil
+	}
+
+	return 0, nil
+}
+
+func (m *memoryTracker) RecordFailure(ctx context.Context, key string, policy Policy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	m.writes++
+	if m.writes%evictEvery == 0 {
+		m.evictExpired(now)
+	}
+
+	a, ok := m.attempts[key]
+	if !ok {
+		a = &attempt{window: policy.Window}
+		m.attempts[key] = a
+	}
+
+	if now.Sub(a.lastFailureAt) > policy.Window {
+		a.failures = 0
+	}
+	a.failures++
+	a.lastFailureAt = now
+
+	if lockout := policy.lockoutFor(a.failures); lockout > 0 {
+		a.lockedUntil = now.Add(lockout)
+	}
+
+	return nil
+}
+
+func (m *memoryTracker) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+
+	return nil
+}
+
+// evictExpired drops keys that are neither locked nor inside their counting
+// window anymore, so the map does not grow with every address ever seen.
+func (m *memoryTracker) evictExpired(now time.Time) {
+	for key, a := range m.attempts {
+		if now.After(a.lockedUntil) && now.Sub(a.lastFailureAt) > a.window {
+			delete(m.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package loginguard
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+type postgresTracker struct {
+	db *sql.DB
+	// writes counts recorded failures, like in memoryTracker, to sweep the
+	// table every evictEvery of them
+	writes atomic.Int64
+}
+
+// NewPostgresTracker keeps the counters in the login_attempts table so they
+// are shared between instances.
+func NewPostgresTracker(db *sql.DB) Tracker {
+	return &postgresTracker{
+		db: db,
+	}
+}
+
+func (p *postgresTracker) Locked(ctx context.Context, key string) (time.Duration, error) {
+	query := `
+		SELECT GREATEST(EXTRACT(EPOCH FROM locked_until - now()), 0)
+		FROM login_attempts
+		WHERE key = $1
+			AND locked_until > now()
+	`
+
+	var seconds float64
+	err := p.db.QueryRowContext(ctx, query, key).Scan(&seconds)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func (p *postgresTracker) RecordFailure(ctx context.Context, key string, policy Policy) error {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at, window_seconds)
+		VALUES ($1, 1, now(), $2)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failure_at < now() - make_interval(secs => $2) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = now(),
+			window_seconds = $2
+		RETURNING failures
+	`
+
+	var failures int
+	err := p.db.QueryRowContext(ctx, query, key, policy.Window.Seconds()).Scan(&failures)
+	if err != nil {
+		return err
+	}
+
+	if p.writes.Add(1)%evictEvery == 0 {
+		if err := p.evictExpired(ctx); err != nil {
+			return err
+		}
+	}
+
+	lockout := policy.lockoutFor(failures)
+	if lockout == 0 {
+		return nil
+	}
+
+	queryLock := `
+		UPDATE login_attempts
+		SET locked_until = now() + make_interval(secs => $2)
+		WHERE key = $1
+	`
+	_, err = p.db.ExecContext(ctx, queryLock, key, lockout.Seconds())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *postgresTracker) Reset(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key = $1`, key)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// evictExpired deletes keys that are neither locked nor inside their
+// counting window anymore, so the table does not grow with every address
+// ever seen.
+func (p *postgresTracker) evictExpired(ctx context.Context) error {
+	query := `
+		DELETE FROM login_attempts
+		WHERE (locked_until IS NULL OR locked_until < now())
+			AND last_failure_at < now() - make_interval(secs => window_seconds)
+	`
+
+	_, err := p.db.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers transactional emails. Implementations must be safe for
+// concurrent use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSenderFromEnv picks the sender configured by MAIL_SENDER. Only local
+// development senders exist for now, "log" (default) and "file".
+func NewSenderFromEnv() Sender {
+	switch os.Getenv("MAIL_SENDER") {
+	case "file":
+		dir := os.Getenv("MAIL_FILE_DIR")
+		if dir == "" {
+			dir = "tmp/mail"
+		}
+		return NewFileSender(dir)
+	default:
+		return NewLogSender()
+	}
+}
+
+type logSender struct{}
+
+func NewLogSender() Sender {
+	return &logSender{}
+}
+
+func (l *logSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to=%q subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+type fileSender struct {
+	dir string
+}
+
+// NewFileSender writes every message as a .eml file into dir.
+func NewFileSender(dir string) Sender {
+	return &fileSender{
+		dir: dir,
+	}
+}
+
+func (f *fileSender) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102150405"), uuid.NewString())
+
+	return os.WriteFile(filepath.Join(f.dir, name), []byte(b.String()), 0o644)
+}
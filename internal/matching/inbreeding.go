@@ -0,0 +1,75 @@
+package matching
+
+import (
+	"cats-social/internal/domain"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// What happens to a match request between related cats.
+const (
+	InbreedingBlock = "block"
+	InbreedingWarn  = "warn"
+)
+
+// InbreedingPolicy decides about match requests between cats that share an
+// ancestor within Generations generations, counting the parents as the
+// first. A cat matched with its own ancestor counts too. Generations 0
+// turns the check off.
+type InbreedingPolicy struct {
+	Generations int
+	Action      string
+}
+
+var DefaultInbreedingPolicy = InbreedingPolicy{
+	Generations: 3,
+	Action:      InbreedingBlock,
+}
+
+// InbreedingPolicyFromEnv starts from DefaultInbreedingPolicy and applies
+// INBREEDING_GENERATIONS and INBREEDING_ACTION (block or warn).
+func InbreedingPolicyFromEnv() (InbreedingPolicy, error) {
+	policy := DefaultInbreedingPolicy
+
+	if v := os.Getenv("INBREEDING_GENERATIONS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > domain.CatPedigreeMaxGenerations {
+			return policy, fmt.Errorf("INBREEDING_GENERATIONS should be between 0 and %d, got %q", domain.CatPedigreeMaxGenerations, v)
+		}
+		policy.Generations = n
+	}
+
+	if v := os.Getenv("INBREEDING_ACTION"); v != "" {
+		if v != InbreedingBlock && v != InbreedingWarn {
+			return policy, fmt.Errorf("INBREEDING_ACTION should be block or warn, got %q", v)
+		}
+		policy.Action = v
+	}
+
+	return policy, nil
+}
+
+// InbreedingMessage tells which ancestor two cats share, the closest of
+// ancestors.
+func InbreedingMessage(ancestors []domain.CommonAncestor) string {
+	ancestor := ancestors[0]
+	switch {
+	case ancestor.Generation == 0:
+		return fmt.Sprintf("%s is an ancestor of the other cat, %s back", ancestor.Name, generationsBack(ancestor.OtherGeneration))
+	case ancestor.OtherGeneration == 0:
+		return fmt.Sprintf("%s is an ancestor of the other cat, %s back", ancestor.Name, generationsBack(ancestor.Generation))
+	case ancestor.Generation == ancestor.OtherGeneration:
+		return fmt.Sprintf("the cats share the ancestor %s, %s back", ancestor.Name, generationsBack(ancestor.Generation))
+	default:
+		return fmt.Sprintf("the cats share the ancestor %s, %s and %s back", ancestor.Name, generationsBack(ancestor.Generation), generationsBack(ancestor.OtherGeneration))
+	}
+}
+
+func generationsBack(n int) string {
+	if n == 1 {
+		return "1 generation"
+	}
+
+	return fmt.Sprintf("%d generations", n)
+}
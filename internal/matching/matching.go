@@ -0,0 +1,149 @@
+// Package matching scores how well cats suit each other as partners.
+package matching
+
+import (
+	"cats-social/internal/domain"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Scorer rates candidate as a partner for cat from 0 to 1, with the reasons
+// behind the score, strongest first. Whether the two may be matched at all
+// is decided before, a Scorer only ranks.
+type Scorer interface {
+	Score(cat *domain.Cat, candidate *domain.Cat, prefs domain.RecommendationPreferences) (float64, []string)
+}
+
+// Factor scores one aspect of a pair from 0 to 1, with a reason when the
+// aspect speaks for the pair. Aspects that cannot be judged, like distance
+// without locations, score neutral.
+type Factor struct {
+	Weight float64
+	Score  func(cat *domain.Cat, candidate *domain.Cat, prefs domain.RecommendationPreferences) (float64, string)
+}
+
+const neutral = 0.5
+
+// WeightedScorer is the weighted mean of its factors.
+type WeightedScorer struct {
+	Factors []Factor
+}
+
+// NewDefaultScorer weighs shared traits, age, breed and distance.
+func NewDefaultScorer() Scorer {
+	return WeightedScorer{
+		Factors: []Factor{
+			{Weight: 0.35, Score: TraitScore},
+			{Weight: 0.25, Score: AgeScore},
+			{Weight: 0.2, Score: BreedScore},
+			{Weight: 0.2, Score: DistanceScore},
+		},
+	}
+}
+
+func (w WeightedScorer) Score(cat *domain.Cat, candidate *domain.Cat, prefs domain.RecommendationPreferences) (float64, []string) {
+	type reason struct {
+		text   string
+		weight float64
+	}
+
+	var total, weights float64
+	var reasons []reason
+	for _, factor := range w.Factors {
+		score, text := factor.Score(cat, candidate, prefs)
+		total += factor.Weight * score
+		weights += factor.Weight
+		if text != "" {
+			reasons = append(reasons, reason{text: text, weight: factor.Weight * score})
+		}
+	}
+
+	sort.SliceStable(reasons, func(i, j int) bool {
+		return reasons[i].weight > reasons[j].weight
+	})
+	texts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		texts = append(texts, reason.text)
+	}
+
+	if weights == 0 {
+		return 0, texts
+	}
+	return total / weights, texts
+}
+
+// TraitScore is the share of traits the cats have in common.
+func TraitScore(cat *domain.Cat, candidate *domain.Cat, _ domain.RecommendationPreferences) (float64, string) {
+	if len(cat.Traits) == 0 || len(candidate.Traits) == 0 {
+		return neutral, ""
+	}
+
+	shared := []string{}
+	for _, trait := range candidate.Traits {
+		if slices.Contains(cat.Traits, trait) {
+			shared = append(shared, trait)
+		}
+	}
+	union := len(cat.Traits) + len(candidate.Traits) - len(shared)
+
+	if len(shared) == 0 {
+		return 0, ""
+	}
+	return float64(len(shared)) / float64(union), "shares traits " + strings.Join(shared, ", ")
+}
+
+// ageGapMonths is the age gap at which AgeScore reaches 0.
+const ageGapMonths = 36
+
+// AgeScore falls the further apart the cats are in age.
+func AgeScore(cat *domain.Cat, candidate *domain.Cat, _ domain.RecommendationPreferences) (float64, string) {
+	gap := cat.AgeInMonth - candidate.AgeInMonth
+	if gap < 0 {
+		gap = -gap
+	}
+
+	score := max(0, 1-float64(gap)/ageGapMonths)
+	switch {
+	case gap == 0:
+		return score, "same age"
+	case gap <= 6:
+		return score, fmt.Sprintf("%d months apart in age", gap)
+	default:
+		return score, ""
+	}
+}
+
+// BreedScore prefers the breeds asked for, or else the cat's own breed.
+func BreedScore(cat *domain.Cat, candidate *domain.Cat, prefs domain.RecommendationPreferences) (float64, string) {
+	if len(prefs.Races) == 0 {
+		if candidate.Race == cat.Race {
+			return 1, "same breed " + candidate.Race
+		}
+		return 0, ""
+	}
+
+	if slices.Contains(prefs.Races, candidate.Race) {
+		return 1, "preferred breed " + candidate.Race
+	}
+	return 0, ""
+}
+
+// distanceRangeKm is the distance at which DistanceScore reaches 0.
+const distanceRangeKm = 100
+
+// DistanceScore prefers cats nearby.
+func DistanceScore(cat *domain.Cat, candidate *domain.Cat, _ domain.RecommendationPreferences) (float64, string) {
+	from, to := cat.Location.Point(), candidate.Location.Point()
+	if from == nil || to == nil {
+		return neutral, ""
+	}
+
+	distance := from.DistanceKm(*to)
+	score := max(0, 1-distance/distanceRangeKm)
+	if distance > distanceRangeKm/2 {
+		return score, ""
+	}
+	return score, fmt.Sprintf("%.1f km away", distance)
+}
@@ -0,0 +1,305 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	testClientID     = "cats-social"
+	testClientSecret = "secret"
+	testRedirectURL  = "http://localhost:8080/v1/user/oidc/callback"
+	testCode         = "the-code"
+	testVerifier     = "the-verifier"
+	testNonce        = "the-nonce"
+)
+
+// mockProvider serves discovery, JWKS and a token endpoint that hands out
+// idToken for testCode and testVerifier.
+type mockProvider struct {
+	server *httptest.Server
+	// issuer is what discovery reports, the server URL unless changed
+	issuer string
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PrivateKey
+	jwksFetches int
+	idToken     string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	m := &mockProvider{keys: map[string]*rsa.PrivateKey{}}
+	m.addKey(t, "k1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.issuer,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.jwksFetches++
+		set := jwkSet{}
+		for kid, key := range m.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("grant_type") != "authorization_code" ||
+			r.PostForm.Get("code") != testCode ||
+			r.PostForm.Get("code_verifier") != testVerifier ||
+			r.PostForm.Get("redirect_uri") != testRedirectURL ||
+			r.PostForm.Get("client_id") != testClientID ||
+			r.PostForm.Get("client_secret") != testClientSecret {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken, "token_type": "Bearer"})
+	})
+
+	m.server = httptest.NewServer(mux)
+	m.issuer = m.server.URL
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockProvider) addKey(t *testing.T, kid string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[kid] = key
+}
+
+// claims returns valid ID token claims, for the test to change.
+func (m *mockProvider) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            testClientID,
+		"sub":            "user-1",
+		"email":          "kitty@example.com",
+		"email_verified": true,
+		"name":           "Kitty Owner",
+		"nonce":          testNonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func (m *mockProvider) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	m.mu.Lock()
+	key := m.keys[kid]
+	m.mu.Unlock()
+	if key == nil {
+		// signed with a key the provider does not publish
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return raw
+}
+
+func (m *mockProvider) config() Config {
+	return Config{
+		IssuerURL:    m.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	m := newMockProvider(t)
+
+	p, err := NewProvider(context.Background(), m.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Issuer() != m.server.URL {
+		t.Errorf("Issuer() = %q, want %q", p.Issuer(), m.server.URL)
+	}
+
+	m.issuer = "https://elsewhere.example.com"
+	if _, err := NewProvider(context.Background(), m.config()); err == nil {
+		t.Error("NewProvider accepted discovery for another issuer")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	m := newMockProvider(t)
+	m.idToken = "the-id-token"
+
+	p, err := NewProvider(context.Background(), m.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idToken, err := p.Exchange(context.Background(), testCode, testVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idToken != "the-id-token" {
+		t.Errorf("Exchange() = %q, want the id token", idToken)
+	}
+
+	if _, err := p.Exchange(context.Background(), testCode, "another-verifier"); err == nil {
+		t.Error("Exchange succeeded with a wrong code verifier")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	m := newMockProvider(t)
+
+	tests := []struct {
+		name   string
+		kid    string
+		change func(claims jwt.MapClaims)
+		nonce  string
+		valid  bool
+	}{
+		{name: "valid", kid: "k1", nonce: testNonce, valid: true},
+		{name: "audience in a list", kid: "k1", nonce: testNonce, valid: true, change: func(c jwt.MapClaims) {
+			c["aud"] = []string{"another-client", testClientID}
+		}},
+		{name: "wrong issuer", kid: "k1", nonce: testNonce, change: func(c jwt.MapClaims) {
+			c["iss"] = "https://elsewhere.example.com"
+		}},
+		{name: "wrong audience", kid: "k1", nonce: testNonce, change: func(c jwt.MapClaims) {
+			c["aud"] = "another-client"
+		}},
+		{name: "wrong nonce", kid: "k1", nonce: "another-nonce"},
+		{name: "no nonce", kid: "k1", nonce: "", change: func(c jwt.MapClaims) {
+			delete(c, "nonce")
+		}},
+		{name: "expired", kid: "k1", nonce: testNonce, change: func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-time.Minute).Unix()
+		}},
+		{name: "no expiry", kid: "k1", nonce: testNonce, change: func(c jwt.MapClaims) {
+			delete(c, "exp")
+		}},
+		{name: "no subject", kid: "k1", nonce: testNonce, change: func(c jwt.MapClaims) {
+			delete(c, "sub")
+		}},
+		{name: "key not published", kid: "unknown", nonce: testNonce},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProvider(context.Background(), m.config())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims := m.claims()
+			if tt.change != nil {
+				tt.change(claims)
+			}
+
+			got, err := p.VerifyIDToken(context.Background(), m.sign(t, tt.kid, claims), tt.nonce)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Errorf("VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := Claims{Subject: "user-1", Email: "kitty@example.com", EmailVerified: true, Name: "Kitty Owner"}
+			if *got != want {
+				t.Errorf("VerifyIDToken() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRefetchesKeys(t *testing.T) {
+	m := newMockProvider(t)
+
+	p, err := NewProvider(context.Background(), m.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.VerifyIDToken(context.Background(), m.sign(t, "k1", m.claims()), testNonce); err != nil {
+		t.Fatal(err)
+	}
+	if m.jwksFetches != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", m.jwksFetches)
+	}
+
+	// the provider rotates to a new key
+	m.addKey(t, "k2")
+	rotated := m.sign(t, "k2", m.claims())
+
+	// right after a fetch an unknown kid is refused without asking again
+	if _, err := p.VerifyIDToken(context.Background(), rotated, testNonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+	}
+	if m.jwksFetches != 1 {
+		t.Fatalf("JWKS fetched %d times within a minute, want 1", m.jwksFetches)
+	}
+
+	p.keysFetched = time.Now().Add(-2 * time.Minute)
+	if _, err := p.VerifyIDToken(context.Background(), rotated, testNonce); err != nil {
+		t.Fatalf("token signed with the rotated key: %s", err)
+	}
+	if m.jwksFetches != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", m.jwksFetches)
+	}
+}
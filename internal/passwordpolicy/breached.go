@@ -0,0 +1,159 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// PrefixLength is how many hex characters of a SHA-1 hash are sent to a
+// BreachedSource, as in the k-anonymity model of Pwned Passwords.
+const PrefixLength = 5
+
+// BreachedSource returns the hash suffixes of breached passwords whose
+// uppercase SHA-1 hash starts with prefix. It never sees the full hash.
+type BreachedSource interface {
+	Range(ctx context.Context, prefix string) ([]string, error)
+}
+
+func IsBreached(ctx context.Context, source BreachedSource, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := source.Range(ctx, hash[:PrefixLength])
+	if err != nil {
+		return false, err
+	}
+
+	for _, suffix := range suffixes {
+		if suffix == hash[PrefixLength:] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+type hashFile struct {
+	file *os.File
+	size int64
+}
+
+// OpenHashFile serves ranges from a local file of "HASH:COUNT" lines sorted
+// by hash, the format of the Pwned Passwords downloader (SHA-1, uppercase).
+// Ranges are found by binary search, so the file is never loaded in memory.
+func OpenHashFile(path string) (BreachedSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached password file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("open breached password file: %w", err)
+	}
+
+	return &hashFile{file: file, size: info.Size()}, nil
+}
+
+func (h *hashFile) Range(ctx context.Context, prefix string) ([]string, error) {
+	prefix = strings.ToUpper(prefix)
+
+	start, err := h.firstLineFrom(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	suffixes := []string{}
+	scanner := bufio.NewScanner(io.NewSectionReader(h.file, start, h.size-start))
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(scanner.Text(), ":")
+		hash = strings.TrimSpace(hash)
+		if !strings.HasPrefix(hash, prefix) {
+			break
+		}
+
+		suffixes = append(suffixes, hash[len(prefix):])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return suffixes, nil
+}
+
+// firstLineFrom returns the offset of the first line whose hash is not below
+// prefix, or the file size when there is none.
+func (h *hashFile) firstLineFrom(prefix string) (int64, error) {
+	// lines before lo are below prefix, the line at hi is not
+	lo, hi := int64(0), h.size
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, err := h.lineStartFrom(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			// no line starts in [mid, hi), step over the line at lo instead
+			start = lo
+		}
+
+		hash, next, err := h.lineAt(start)
+		if err != nil {
+			return 0, err
+		}
+
+		if hash < prefix {
+			lo = next
+		} else {
+			hi = start
+		}
+	}
+
+	return lo, nil
+}
+
+// lineStartFrom returns the offset of the first line starting at or after pos.
+func (h *hashFile) lineStartFrom(pos int64) (int64, error) {
+	if pos == 0 {
+		return 0, nil
+	}
+
+	buf := make([]byte, 128)
+	for pos < h.size {
+		n, err := h.file.ReadAt(buf, pos-1)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return pos + int64(i), nil
+		}
+		pos += int64(n)
+	}
+
+	return h.size, nil
+}
+
+// lineAt returns the hash of the line starting at start and the offset of
+// the next line.
+func (h *hashFile) lineAt(start int64) (string, int64, error) {
+	reader := bufio.NewReader(io.NewSectionReader(h.file, start, h.size-start))
+
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+
+	hash, _, _ := strings.Cut(line, ":")
+
+	return strings.TrimSpace(hash), start + int64(len(line)), nil
+}
@@ -0,0 +1,192 @@
+package passwordpolicy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// hashes of the test file, sorted; "password" is the second one
+var testHashes = []string{
+	"0000A1B2C3D4E5F60718293A4B5C6D7E8F901234",
+	"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8",
+	"5BAA6FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+	"C0FFEE0000000000000000000000000000000000",
+	"FFFFF00000000000000000000000000000000000",
+}
+
+// openTestHashFile writes testHashes as "HASH:COUNT" lines and opens them.
+func openTestHashFile(t *testing.T, trailingNewline bool) *hashFile {
+	t.Helper()
+
+	lines := make([]string, 0, len(testHashes))
+	for i, hash := range testHashes {
+		lines = append(lines, hash+":"+strings.Repeat("9", i+1))
+	}
+	content := strings.Join(lines, "\r\n")
+	if trailingNewline {
+		content += "\r\n"
+	}
+
+	path := filepath.Join(t.TempDir(), "hashes.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := OpenHashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { source.(*hashFile).file.Close() })
+
+	return source.(*hashFile)
+}
+
+// lineOffsets returns where each line of a test file starts.
+func lineOffsets(t *testing.T, h *hashFile) []int64 {
+	t.Helper()
+
+	offsets := []int64{}
+	for pos := int64(0); pos < h.size; {
+		offsets = append(offsets, pos)
+		_, next, err := h.lineAt(pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pos = next
+	}
+	if len(offsets) != len(testHashes) {
+		t.Fatalf("test file has %d lines, want %d", len(offsets), len(testHashes))
+	}
+
+	return offsets
+}
+
+func TestFirstLineFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		// line is the index of the expected line, len(testHashes) for the
+		// end of the file
+		line int
+	}{
+		{"first line", "0000A", 0},
+		{"middle line", "5BAA6", 1},
+		{"last line", "FFFFF", 4},
+		{"missing below every hash", "00000", 0},
+		{"missing between hashes", "A0000", 3},
+		{"missing above every hash", "FFFFF1", 5},
+	}
+
+	for _, trailingNewline := range []bool{true, false} {
+		h := openTestHashFile(t, trailingNewline)
+		offsets := append(lineOffsets(t, h), h.size)
+
+		for _, tt := range tests {
+			name := tt.name
+			if !trailingNewline {
+				name += " without trailing newline"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				got, err := h.firstLineFrom(tt.prefix)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := offsets[tt.line]; got != want {
+					t.Errorf("firstLineFrom(%q) = %d, want %d", tt.prefix, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestLineStartFrom(t *testing.T) {
+	for _, trailingNewline := range []bool{true, false} {
+		h := openTestHashFile(t, trailingNewline)
+		offsets := lineOffsets(t, h)
+		last := offsets[len(offsets)-1]
+
+		tests := []struct {
+			name string
+			pos  int64
+			want int64
+		}{
+			{"start of the file", 0, 0},
+			{"inside the first line", 1, offsets[1]},
+			{"start of a line", offsets[2], offsets[2]},
+			{"at the end of a line", offsets[2] - 1, offsets[2]},
+			{"start of the last line", last, last},
+			{"inside the last line", last + 1, h.size},
+			{"end of the file", h.size, h.size},
+		}
+
+		for _, tt := range tests {
+			name := tt.name
+			if !trailingNewline {
+				name += " without trailing newline"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				got, err := h.lineStartFrom(tt.pos)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("lineStartFrom(%d) = %d, want %d", tt.pos, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestHashFileRange(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"0000a", []string{"1B2C3D4E5F60718293A4B5C6D7E8F901234"}},
+		{"5BAA6", []string{"1E4C9B93F3F0682250B6CF8331B7EE68FD8", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF"}},
+		{"FFFFF", []string{"00000000000000000000000000000000000"}},
+		{"12345", []string{}},
+	}
+
+	for _, trailingNewline := range []bool{true, false} {
+		h := openTestHashFile(t, trailingNewline)
+
+		for _, tt := range tests {
+			got, err := h.Range(context.Background(), tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Range(%q) = %v, want %v (trailing newline %t)", tt.prefix, got, tt.want, trailingNewline)
+			}
+		}
+	}
+}
+
+func TestIsBreached(t *testing.T) {
+	h := openTestHashFile(t, false)
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"password", true},
+		{"correct horse battery staple", false},
+	}
+
+	for _, tt := range tests {
+		got, err := IsBreached(context.Background(), h, tt.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("IsBreached(%q) = %t, want %t", tt.password, got, tt.want)
+		}
+	}
+}
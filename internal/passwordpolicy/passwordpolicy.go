@@ -0,0 +1,219 @@
+// Package passwordpolicy decides which passwords users may choose.
+package passwordpolicy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	RuleRequired     = "required"
+	RuleMinLength    = "min_length"
+	RuleMaxLength    = "max_length"
+	RuleUpper        = "upper"
+	RuleLower        = "lower"
+	RuleDigit        = "digit"
+	RuleSymbol       = "symbol"
+	RulePersonalInfo = "personal_info"
+	RuleBreached     = "breached"
+)
+
+// bcrypt only looks at the first 72 bytes, longer passwords are rejected
+// instead of silently truncated.
+const maxBytes = 72
+
+// Violation is a rule a password does not satisfy.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Policy lists the rules a password has to pass. Lengths count characters,
+// not bytes.
+type Policy struct {
+	MinLength          int
+	MaxLength          int
+	RequireUpper       bool
+	RequireLower       bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	ForbidPersonalInfo bool
+}
+
+var DefaultPolicy = Policy{
+	MinLength:          8,
+	MaxLength:          64,
+	ForbidPersonalInfo: true,
+}
+
+// PolicyFromEnv starts from DefaultPolicy and applies PASSWORD_MIN_LENGTH,
+// PASSWORD_MAX_LENGTH, PASSWORD_REQUIRED_CLASSES (a comma separated list of
+// upper, lower, digit and symbol) and PASSWORD_FORBID_PERSONAL_INFO.
+func PolicyFromEnv() (Policy, error) {
+	policy := DefaultPolicy
+
+	if v := os.Getenv("PASSWORD_MIN_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return policy, fmt.Errorf("PASSWORD_MIN_LENGTH should be a positive number, got %q", v)
+		}
+		policy.MinLength = n
+	}
+	if v := os.Getenv("PASSWORD_MAX_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < policy.MinLength {
+			return policy, fmt.Errorf("PASSWORD_MAX_LENGTH should be a number not below the minimum length, got %q", v)
+		}
+		policy.MaxLength = n
+	}
+
+	if v := os.Getenv("PASSWORD_REQUIRED_CLASSES"); v != "" {
+		for _, class := range strings.Split(v, ",") {
+			switch strings.TrimSpace(class) {
+			case RuleUpper:
+				policy.RequireUpper = true
+			case RuleLower:
+				policy.RequireLower = true
+			case RuleDigit:
+				policy.RequireDigit = true
+			case RuleSymbol:
+				policy.RequireSymbol = true
+			default:
+				return policy, fmt.Errorf("PASSWORD_REQUIRED_CLASSES: unknown class %q", class)
+			}
+		}
+	}
+
+	if v := os.Getenv("PASSWORD_FORBID_PERSONAL_INFO"); v != "" {
+		forbid, err := strconv.ParseBool(v)
+		if err != nil {
+			return policy, fmt.Errorf("PASSWORD_FORBID_PERSONAL_INFO should be true or false, got %q", v)
+		}
+		policy.ForbidPersonalInfo = forbid
+	}
+
+	return policy, nil
+}
+
+// Validate returns every rule the password breaks. personalInfo holds values
+// the password must not contain, e.g. the user's email and name.
+func (p Policy) Validate(password string, personalInfo ...string) []Violation {
+	violations := []Violation{}
+
+	if password == "" {
+		return append(violations, Violation{Rule: RuleRequired, Message: "password not be empty"})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{Rule: RuleMinLength, Message: fmt.Sprintf("password should be at least %d characters", p.MinLength)})
+	}
+	if length > p.MaxLength || len(password) > maxBytes {
+		violations = append(violations, Violation{Rule: RuleMaxLength, Message: fmt.Sprintf("password should be at most %d characters", min(p.MaxLength, maxBytes))})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, Violation{Rule: RuleUpper, Message: "password should contain an uppercase letter"})
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, Violation{Rule: RuleLower, Message: "password should contain a lowercase letter"})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{Rule: RuleDigit, Message: "password should contain a digit"})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{Rule: RuleSymbol, Message: "password should contain a symbol"})
+	}
+
+	if p.ForbidPersonalInfo && containsPersonalInfo(password, personalInfo) {
+		violations = append(violations, Violation{Rule: RulePersonalInfo, Message: "password should not contain your email or name"})
+	}
+
+	return violations
+}
+
+// containsPersonalInfo checks the local part of emails, whole names and the
+// longer words of names. Very short parts are ignored, they would match too
+// many passwords by accident.
+func containsPersonalInfo(password string, personalInfo []string) bool {
+	lowered := strings.ToLower(password)
+
+	for _, info := range personalInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		if local, _, found := strings.Cut(info, "@"); found {
+			info = local
+		}
+
+		parts := append([]string{info}, strings.Fields(info)...)
+		for _, part := range parts {
+			if utf8.RuneCountInString(part) >= 4 && strings.Contains(lowered, part) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Checker validates passwords against a policy and, when configured, a list
+// of breached passwords.
+type Checker struct {
+	Policy   Policy
+	Breached BreachedSource
+}
+
+// NewCheckerFromEnv reads the policy from the environment and the breached
+// password list from BREACHED_PASSWORDS_FILE when it is set.
+func NewCheckerFromEnv() (*Checker, error) {
+	policy, err := PolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	checker := &Checker{Policy: policy}
+
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		checker.Breached, err = OpenHashFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return checker, nil
+}
+
+func (c *Checker) Check(ctx context.Context, password string, personalInfo ...string) ([]Violation, error) {
+	violations := c.Policy.Validate(password, personalInfo...)
+	if len(violations) > 0 || c.Breached == nil {
+		return violations, nil
+	}
+
+	breached, err := IsBreached(ctx, c.Breached, password)
+	if err != nil {
+		return nil, err
+	}
+	if breached {
+		violations = append(violations, Violation{Rule: RuleBreached, Message: "password appeared in a data breach, choose another one"})
+	}
+
+	return violations, nil
+}
@@ -0,0 +1,86 @@
+package passwordpolicy
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestContainsPersonalInfo(t *testing.T) {
+	tests := []struct {
+		name         string
+		password     string
+		personalInfo []string
+		want         bool
+	}{
+		{"no personal info", "tabby-cat-42", nil, false},
+		{"email local part", "xJohnDoe99!", []string{"johndoe@example.com"}, true},
+		{"email domain is ignored", "example.com!1", []string{"johndoe@example.com"}, false},
+		{"whole name", "i am mo li", []string{"Mo Li"}, true},
+		{"whole name without its space", "moli-2024", []string{"Mo Li"}, false},
+		{"longer word of a name", "SMITHsmith1", []string{"Anna Smith"}, true},
+		{"short words are ignored", "annabelle", []string{"Ann Li"}, false},
+		{"short email local part is ignored", "bob12345", []string{"bob@example.com"}, false},
+		{"surrounding spaces are ignored", "kitty-robertson", []string{"  Robertson  "}, true},
+		{"empty info", "anything", []string{"", "   "}, false},
+		{"any of several values", "whiskers-lover", []string{"someone@example.com", "Whiskers Owner"}, true},
+		{"non ascii name counts characters", "xÉlodie!", []string{"Élodie"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsPersonalInfo(tt.password, tt.personalInfo); got != tt.want {
+				t.Errorf("containsPersonalInfo(%q, %q) = %t, want %t", tt.password, tt.personalInfo, got, tt.want)
+			}
+		})
+	}
+}
+
+func rules(violations []Violation) []string {
+	res := []string{}
+	for _, violation := range violations {
+		res = append(res, violation.Rule)
+	}
+
+	return res
+}
+
+func TestValidate(t *testing.T) {
+	strict := Policy{
+		MinLength:          8,
+		MaxLength:          64,
+		RequireUpper:       true,
+		RequireLower:       true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		ForbidPersonalInfo: true,
+	}
+
+	tests := []struct {
+		name         string
+		policy       Policy
+		password     string
+		personalInfo []string
+		want         []string
+	}{
+		{"empty", DefaultPolicy, "", nil, []string{RuleRequired}},
+		{"valid", DefaultPolicy, "purring-along", nil, []string{}},
+		{"too short", DefaultPolicy, "meow", nil, []string{RuleMinLength}},
+		{"length counts characters", DefaultPolicy, "ééééééé", nil, []string{RuleMinLength}},
+		{"too long", DefaultPolicy, strings.Repeat("a", 65), nil, []string{RuleMaxLength}},
+		{"over the bcrypt limit", Policy{MinLength: 8, MaxLength: 100}, strings.Repeat("é", 40), nil, []string{RuleMaxLength}},
+		{"missing classes", strict, "alllowercase", nil, []string{RuleUpper, RuleDigit, RuleSymbol}},
+		{"every class", strict, "Purr-1234", nil, []string{}},
+		{"personal info", DefaultPolicy, "felix-the-cat", []string{"felix@example.com"}, []string{RulePersonalInfo}},
+		{"personal info allowed", Policy{MinLength: 8, MaxLength: 64}, "felix-the-cat", []string{"felix@example.com"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules(tt.policy.Validate(tt.password, tt.personalInfo...))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate(%q) broke %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
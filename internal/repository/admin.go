@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+type AdminRepository interface {
+	GetAllUsers(ctx context.Context, tx *sql.Tx, limit int, offset int) ([]domain.AdminUserResponse, error)
+	SetUserDisabled(ctx context.Context, tx *sql.Tx, userId uuid.UUID, disabled bool) (bool, error)
+	HardDeleteCat(ctx context.Context, tx *sql.Tx, catId uuid.UUID) (bool, error)
+	CancelCatMatch(ctx context.Context, tx *sql.Tx, matchId uuid.UUID) (bool, error)
+	CreateAuditLog(ctx context.Context, tx *sql.Tx, auditLog *domain.AdminAuditLog) error
+}
+
+type adminRepository struct{}
+
+func NewAdminRepository() AdminRepository {
+	return &adminRepository{}
+}
+
+func (a *adminRepository) GetAllUsers(ctx context.Context, tx *sql.Tx, limit int, offset int) ([]domain.AdminUserResponse, error) {
+	query := `
+		SELECT id, email, name, role, email_verified_at IS NOT NULL, disabled_at, deleted_at, created_at
+		FROM users
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := tx.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []domain.AdminUserResponse{}
+	for rows.Next() {
+		var user domain.AdminUserResponse
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.Name,
+			&user.Role,
+			&user.EmailVerified,
+			&user.DisabledAt,
+			&user.DeletedAt,
+			&user.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func (a *adminRepository) SetUserDisabled(ctx context.Context, tx *sql.Tx, userId uuid.UUID, disabled bool) (bool, error) {
+	query := `
+		UPDATE users
+		SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, now()) ELSE NULL END
+		WHERE id = $1
+			AND deleted_at IS NULL
+	`
+
+	res, err := tx.ExecContext(ctx, query, userId, disabled)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// HardDeleteCat removes the cat row for good together with every match
+// request that references it. Partners of an approved match become available
+// for matching again.
+func (a *adminRepository) HardDeleteCat(ctx context.Context, tx *sql.Tx, catId uuid.UUID) (bool, error) {
+	queryResetPartners := `
+		UPDATE cats
+		SET has_matched = false
+		WHERE id IN (
+			SELECT CASE WHEN match_cat_id = $1 THEN user_cat_id ELSE match_cat_id END
+			FROM cat_matches
+			WHERE status = 'approved'
+				AND (match_cat_id = $1 OR user_cat_id = $1)
+		)
+	`
+	_, err := tx.ExecContext(ctx, queryResetPartners, catId)
+	if err != nil {
+		return false, err
+	}
+
+	queryDeleteMatches := `DELETE FROM cat_matches WHERE match_cat_id = $1 OR user_cat_id = $1`
+	_, err = tx.ExecContext(ctx, queryDeleteMatches, catId)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM cats WHERE id = $1`, catId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (a *adminRepository) CancelCatMatch(ctx context.Context, tx *sql.Tx, matchId uuid.UUID) (bool, error) {
+	querySetHasMatched := `
+		UPDATE cats
+		SET has_matched = false
+		WHERE id IN (
+			SELECT match_cat_id
+			FROM cat_matches
+			WHERE id = $1 AND status = 'approved'
+			UNION
+			SELECT user_cat_id
+			FROM cat_matches
+			WHERE id = $1 AND status = 'approved'
+		)
+	`
+	_, err := tx.ExecContext(ctx, querySetHasMatched, matchId)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM cat_matches WHERE id = $1`, matchId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (a *adminRepository) CreateAuditLog(ctx context.Context, tx *sql.Tx, auditLog *domain.AdminAuditLog) error {
+	query := `INSERT INTO admin_audit_logs (id, created_at, admin_id, action, target_type, target_id, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	metadata, err := json.Marshal(auditLog.Metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, auditLog.ID, auditLog.CreatedAt, auditLog.AdminID, auditLog.Action, auditLog.TargetType, auditLog.TargetID, metadata)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ApiKeyRepository interface {
+	CreateApiKey(db *sql.DB, apiKey *domain.ApiKey) error
+	GetApiKeysByUserId(db *sql.DB, userId uuid.UUID) ([]domain.ApiKey, error)
+	RevokeApiKey(db *sql.DB, id uuid.UUID, userId uuid.UUID) (bool, error)
+	GetActiveApiKeyByHash(db *sql.DB, hash string) (*domain.ApiKey, error)
+	TouchApiKey(db *sql.DB, id uuid.UUID) error
+}
+
+type apiKeyRepository struct{}
+
+func NewApiKeyRepository() ApiKeyRepository {
+	return &apiKeyRepository{}
+}
+
+func (a *apiKeyRepository) CreateApiKey(db *sql.DB, apiKey *domain.ApiKey) error {
+	query := `INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := db.Exec(query, apiKey.ID, apiKey.UserID, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, apiKey.Scopes, apiKey.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *apiKeyRepository) GetApiKeysByUserId(db *sql.DB, userId uuid.UUID) ([]domain.ApiKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := db.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	apiKeys := []domain.ApiKey{}
+	m := pgtype.NewMap()
+
+	for rows.Next() {
+		apiKey := domain.ApiKey{}
+
+		err = rows.Scan(&apiKey.ID, &apiKey.UserID, &apiKey.Name, &apiKey.Prefix, m.SQLScanner(&apiKey.Scopes), &apiKey.CreatedAt, &apiKey.LastUsedAt, &apiKey.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, nil
+}
+
+func (a *apiKeyRepository) RevokeApiKey(db *sql.DB, id uuid.UUID, userId uuid.UUID) (bool, error) {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = now()
+		WHERE id = $1
+			AND user_id = $2
+			AND revoked_at IS NULL
+	`
+
+	res, err := db.Exec(query, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (a *apiKeyRepository) GetActiveApiKeyByHash(db *sql.DB, hash string) (*domain.ApiKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, scopes, created_at, last_used_at
+		FROM api_keys
+		WHERE key_hash = $1
+			AND revoked_at IS NULL
+	`
+	apiKey := domain.ApiKey{}
+	m := pgtype.NewMap()
+
+	err := db.QueryRow(query, hash).Scan(&apiKey.ID, &apiKey.UserID, &apiKey.Name, &apiKey.Prefix, m.SQLScanner(&apiKey.Scopes), &apiKey.CreatedAt, &apiKey.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (a *apiKeyRepository) TouchApiKey(db *sql.DB, id uuid.UUID) error {
+	query := `UPDATE api_keys SET last_used_at = now() WHERE id = $1`
+
+	_, err := db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
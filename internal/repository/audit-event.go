@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+type AuditEventRepository interface {
+	CreateAuditEvents(ctx context.Context, db *sql.DB, events []*domain.AuditEvent) error
+	GetAuditEventsByActorId(db *sql.DB, actorId uuid.UUID, limit int, offset int) ([]domain.AuditEventResponse, error)
+}
+
+type auditEventRepository struct{}
+
+func NewAuditEventRepository() AuditEventRepository {
+	return &auditEventRepository{}
+}
+
+// CreateAuditEvents inserts a batch of events in one transaction.
+func (a *auditEventRepository) CreateAuditEvents(ctx context.Context, db *sql.DB, events []*domain.AuditEvent) error {
+	query := `INSERT INTO audit_events (id, created_at, actor_id, ip, user_agent, event_type, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, event := range events {
+		metadata, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return err
+		}
+
+		actorId := uuid.NullUUID{UUID: event.ActorID, Valid: event.ActorID != uuid.Nil}
+
+		_, err = tx.ExecContext(ctx, query, event.ID, event.CreatedAt, actorId, event.IP, event.UserAgent, event.EventType, metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (a *auditEventRepository) GetAuditEventsByActorId(db *sql.DB, actorId uuid.UUID, limit int, offset int) ([]domain.AuditEventResponse, error) {
+	query := `
+		SELECT id, event_type, ip, user_agent, metadata, created_at
+		FROM audit_events
+		WHERE actor_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := db.Query(query, actorId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []domain.AuditEventResponse{}
+	for rows.Next() {
+		var event domain.AuditEventResponse
+		var metadata []byte
+
+		err := rows.Scan(&event.ID, &event.EventType, &event.IP, &event.UserAgent, &metadata, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(metadata, &event.Metadata); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type BreedRepository interface {
+	GetBreeds(db *sql.DB) ([]domain.Breed, error)
+	GetBreed(ctx context.Context, tx *sql.Tx, breedId uuid.UUID) (*domain.Breed, error)
+	CreateBreed(ctx context.Context, tx *sql.Tx, breed *domain.Breed) error
+	UpdateBreed(ctx context.Context, tx *sql.Tx, breed *domain.Breed) error
+	DeleteBreed(ctx context.Context, tx *sql.Tx, breedId uuid.UUID) error
+	CountCatsOfBreed(ctx context.Context, tx *sql.Tx, name string) (int, error)
+}
+
+type breedRepository struct{}
+
+func NewBreedRepository() BreedRepository {
+	return &breedRepository{}
+}
+
+const breedColumns = `id, created_at, name, aliases, size, coat, origin`
+
+// GetBreeds returns the whole catalogue by name.
+func (b *breedRepository) GetBreeds(db *sql.DB) ([]domain.Breed, error) {
+	rows, err := db.Query(`SELECT ` + breedColumns + ` FROM breeds ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breeds := []domain.Breed{}
+	m := pgtype.NewMap()
+	for rows.Next() {
+		breed, err := scanBreed(rows, m)
+		if err != nil {
+			return nil, err
+		}
+
+		breeds = append(breeds, *breed)
+	}
+
+	return breeds, nil
+}
+
+// GetBreed returns sql.ErrNoRows when there is no such breed. The row is
+// locked until the transaction ends.
+func (b *breedRepository) GetBreed(ctx context.Context, tx *sql.Tx, breedId uuid.UUID) (*domain.Breed, error) {
+	row := tx.QueryRowContext(ctx, `SELECT `+breedColumns+` FROM breeds WHERE id = $1 FOR UPDATE`, breedId)
+
+	return scanBreed(row, pgtype.NewMap())
+}
+
+func (b *breedRepository) CreateBreed(ctx context.Context, tx *sql.Tx, breed *domain.Breed) error {
+	query := `
+		INSERT INTO breeds (id, created_at, name, aliases, size, coat, origin)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := tx.ExecContext(ctx, query, breed.ID, breed.CreatedAt, breed.Name, breed.Aliases, nullString(breed.Size), nullString(breed.Coat), breed.Origin)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateBreed also renames the race of every cat of the breed, the foreign
+// key cascades.
+func (b *breedRepository) UpdateBreed(ctx context.Context, tx *sql.Tx, breed *domain.Breed) error {
+	query := `
+		UPDATE breeds
+		SET name = $2, aliases = $3, size = $4, coat = $5, origin = $6
+		WHERE id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, breed.ID, breed.Name, breed.Aliases, nullString(breed.Size), nullString(breed.Coat), breed.Origin)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (b *breedRepository) DeleteBreed(ctx context.Context, tx *sql.Tx, breedId uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM breeds WHERE id = $1`, breedId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CountCatsOfBreed counts deleted cats too, their rows still point at the
+// breed.
+func (b *breedRepository) CountCatsOfBreed(ctx context.Context, tx *sql.Tx, name string) (int, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM cats WHERE race = $1`, name).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func scanBreed(row rowScanner, m *pgtype.Map) (*domain.Breed, error) {
+	breed := domain.Breed{}
+	var size, coat sql.NullString
+
+	err := row.Scan(
+		&breed.ID,
+		&breed.CreatedAt,
+		&breed.Name,
+		m.SQLScanner(&breed.Aliases),
+		&size,
+		&coat,
+		&breed.Origin,
+	)
+	if err != nil {
+		return nil, err
+	}
+	breed.Size = size.String
+	breed.Coat = coat.String
+	if breed.Aliases == nil {
+		breed.Aliases = []string{}
+	}
+
+	return &breed, nil
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
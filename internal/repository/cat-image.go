@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CatImageRepository interface {
+	LockCat(ctx context.Context, tx *sql.Tx, catId uuid.UUID) error
+	GetCatImages(ctx context.Context, tx *sql.Tx, catId uuid.UUID) ([]domain.CatImage, error)
+	GetCatImagesByCatIds(db *sql.DB, catIds []uuid.UUID) ([]domain.CatImage, error)
+	CreateCatImage(ctx context.Context, tx *sql.Tx, image *domain.CatImage) error
+	UpdateCatImagePosition(ctx context.Context, tx *sql.Tx, imageId uuid.UUID, position int) error
+	DeleteCatImage(ctx context.Context, tx *sql.Tx, catId uuid.UUID, imageId uuid.UUID) (*domain.CatImage, error)
+	SyncCatImageUrls(ctx context.Context, tx *sql.Tx, catId uuid.UUID) error
+	ClaimPendingCatImage(ctx context.Context, tx *sql.Tx, leaseUntil time.Time) (*domain.CatImage, error)
+	SaveCatImageVariants(ctx context.Context, tx *sql.Tx, imageId uuid.UUID, variants map[string]domain.CatImageVariant) (bool, error)
+	FailCatImageVariants(ctx context.Context, tx *sql.Tx, imageId uuid.UUID, status string, attempts int, nextAttemptAt time.Time, message string) error
+}
+
+type catImageRepository struct{}
+
+func NewCatImageRepository() CatImageRepository {
+	return &catImageRepository{}
+}
+
+const catImageColumns = `id, cat_id, storage_key, url, content_type, width, height, size_bytes, position, created_at,
+	variants, variants_status, variants_attempts`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCatImage(row rowScanner) (*domain.CatImage, error) {
+	image := domain.CatImage{}
+	var variants []byte
+
+	err := row.Scan(
+		&image.ID,
+		&image.CatID,
+		&image.StorageKey,
+		&image.URL,
+		&image.ContentType,
+		&image.Width,
+		&image.Height,
+		&image.SizeBytes,
+		&image.Position,
+		&image.CreatedAt,
+		&variants,
+		&image.VariantsStatus,
+		&image.VariantsAttempts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(variants, &image.Variants); err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// LockCat serializes changes to the images of a cat until tx ends.
+func (c *catImageRepository) LockCat(ctx context.Context, tx *sql.Tx, catId uuid.UUID) error {
+	query := `SELECT id FROM cats WHERE id = $1 FOR UPDATE`
+
+	var id uuid.UUID
+	return tx.QueryRowContext(ctx, query, catId).Scan(&id)
+}
+
+func (c *catImageRepository) GetCatImages(ctx context.Context, tx *sql.Tx, catId uuid.UUID) ([]domain.CatImage, error) {
+	query := `
+		SELECT ` + catImageColumns + `
+		FROM cat_images
+		WHERE cat_id = $1
+		ORDER BY position, created_at
+	`
+
+	rows, err := tx.QueryContext(ctx, query, catId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []domain.CatImage{}
+	for rows.Next() {
+		image, err := scanCatImage(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, *image)
+	}
+
+	return images, nil
+}
+
+// GetCatImagesByCatIds returns the images of several cats, grouped by cat and
+// in order within each cat.
+func (c *catImageRepository) GetCatImagesByCatIds(db *sql.DB, catIds []uuid.UUID) ([]domain.CatImage, error) {
+	ids := make([]string, 0, len(catIds))
+	for _, id := range catIds {
+		ids = append(ids, id.String())
+	}
+
+	query := `
+		SELECT ` + catImageColumns + `
+		FROM cat_images
+		WHERE cat_id = ANY($1::uuid[])
+		ORDER BY cat_id, position, created_at
+	`
+
+	rows, err := db.Query(query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []domain.CatImage{}
+	for rows.Next() {
+		image, err := scanCatImage(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, *image)
+	}
+
+	return images, nil
+}
+
+func (c *catImageRepository) CreateCatImage(ctx context.Context, tx *sql.Tx, image *domain.CatImage) error {
+	query := `INSERT INTO cat_images (id, cat_id, storage_key, url, content_type, width, height, size_bytes, position, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		image.ID,
+		image.CatID,
+		image.StorageKey,
+		image.URL,
+		image.ContentType,
+		image.Width,
+		image.Height,
+		image.SizeBytes,
+		image.Position,
+		image.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *catImageRepository) UpdateCatImagePosition(ctx context.Context, tx *sql.Tx, imageId uuid.UUID, position int) error {
+	query := `UPDATE cat_images SET position = $2 WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, imageId, position)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCatImage returns the deleted image, or sql.ErrNoRows when the cat has
+// no such image.
+func (c *catImageRepository) DeleteCatImage(ctx context.Context, tx *sql.Tx, catId uuid.UUID, imageId uuid.UUID) (*domain.CatImage, error) {
+	query := `
+		DELETE FROM cat_images
+		WHERE id = $1
+			AND cat_id = $2
+		RETURNING ` + catImageColumns
+
+	return scanCatImage(tx.QueryRowContext(ctx, query, imageId, catId))
+}
+
+// SyncCatImageUrls copies the image urls, in order, into cats.image_urls.
+func (c *catImageRepository) SyncCatImageUrls(ctx context.Context, tx *sql.Tx, catId uuid.UUID) error {
+	query := `
+		UPDATE cats
+		SET image_urls = COALESCE((
+			SELECT array_agg(url ORDER BY position, created_at)
+			FROM cat_images
+			WHERE cat_id = $1
+		), '{}')
+		WHERE id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, catId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ClaimPendingCatImage takes the next image of a cat that is not deleted
+// whose variants are due and counts the attempt. The image is not due again
+// before leaseUntil, so once tx commits no other worker takes it while it is
+// being processed, and a worker that dies leaves it to be retried. Images
+// locked by other transactions are skipped. It returns sql.ErrNoRows when
+// nothing is due.
+func (c *catImageRepository) ClaimPendingCatImage(ctx context.Context, tx *sql.Tx, leaseUntil time.Time) (*domain.CatImage, error) {
+	query := `
+		UPDATE cat_images
+		SET variants_attempts = variants_attempts + 1,
+			variants_next_attempt_at = $1
+		WHERE id = (
+			SELECT id
+			FROM cat_images
+			WHERE variants_status = 'pending'
+				AND variants_next_attempt_at <= now()
+				AND cat_id IN (SELECT id FROM cats WHERE deleted = false)
+			ORDER BY variants_next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + catImageColumns + `
+	`
+
+	return scanCatImage(tx.QueryRowContext(ctx, query, leaseUntil))
+}
+
+// SaveCatImageVariants reports false when the image or its cat is gone,
+// deleted while its variants were made.
+func (c *catImageRepository) SaveCatImageVariants(ctx context.Context, tx *sql.Tx, imageId uuid.UUID, variants map[string]domain.CatImageVariant) (bool, error) {
+	query := `
+		UPDATE cat_images
+		SET variants = $2,
+			variants_status = 'ready',
+			variants_error = NULL
+		WHERE id = $1
+			AND cat_id IN (SELECT id FROM cats WHERE deleted = false)
+	`
+
+	data, err := json.Marshal(variants)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.ExecContext(ctx, query, imageId, data)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// FailCatImageVariants records why an attempt failed. With status pending
+// the image is claimed again at nextAttemptAt.
+func (c *catImageRepository) FailCatImageVariants(ctx context.Context, tx *sql.Tx, imageId uuid.UUID, status string, attempts int, nextAttemptAt time.Time, message string) error {
+	query := `
+		UPDATE cat_images
+		SET variants_status = $2,
+			variants_attempts = $3,
+			variants_next_attempt_at = $4,
+			variants_error = $5
+		WHERE id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, imageId, status, attempts, nextAttemptAt, message)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
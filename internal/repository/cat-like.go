@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type CatLikeRepository interface {
+	GetCatOwnerId(ctx context.Context, tx *sql.Tx, catId uuid.UUID) (uuid.UUID, error)
+	LikeCat(ctx context.Context, tx *sql.Tx, userId uuid.UUID, catId uuid.UUID) error
+	UnlikeCat(ctx context.Context, tx *sql.Tx, userId uuid.UUID, catId uuid.UUID) error
+	CountCatLikes(ctx context.Context, tx *sql.Tx, catId uuid.UUID) (int, error)
+	GetLikedCats(ctx context.Context, tx *sql.Tx, userId uuid.UUID, page domain.PageRequest) ([]domain.LikedCat, error)
+	CountLikedCats(ctx context.Context, tx *sql.Tx, userId uuid.UUID) (int, error)
+	GetCatLikers(ctx context.Context, tx *sql.Tx, catId uuid.UUID, page domain.PageRequest) ([]domain.CatLiker, error)
+	DeleteCatLikesByUserID(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+}
+
+type catLikeRepository struct{}
+
+func NewCatLikeRepository() CatLikeRepository {
+	return &catLikeRepository{}
+}
+
+// GetCatOwnerId returns sql.ErrNoRows when the cat does not exist or is
+// deleted.
+func (c *catLikeRepository) GetCatOwnerId(ctx context.Context, tx *sql.Tx, catId uuid.UUID) (uuid.UUID, error) {
+	query := `SELECT owned_by_id FROM cats WHERE id = $1 AND deleted = false`
+
+	var ownerId uuid.UUID
+	err := tx.QueryRowContext(ctx, query, catId).Scan(&ownerId)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return ownerId, nil
+}
+
+// LikeCat does nothing when the user already likes the cat.
+func (c *catLikeRepository) LikeCat(ctx context.Context, tx *sql.Tx, userId uuid.UUID, catId uuid.UUID) error {
+	query := `
+		INSERT INTO cat_likes (user_id, cat_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, cat_id) DO NOTHING
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId, catId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *catLikeRepository) UnlikeCat(ctx context.Context, tx *sql.Tx, userId uuid.UUID, catId uuid.UUID) error {
+	query := `DELETE FROM cat_likes WHERE user_id = $1 AND cat_id = $2`
+
+	_, err := tx.ExecContext(ctx, query, userId, catId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *catLikeRepository) CountCatLikes(ctx context.Context, tx *sql.Tx, catId uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM cat_likes WHERE cat_id = $1`
+
+	var count int
+	err := tx.QueryRowContext(ctx, query, catId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetLikedCats returns the cats the user likes that are not deleted, most
+// recently liked first, one more than page.Limit when there are more.
+// Reading backward from a cursor the page comes in reverse.
+func (c *catLikeRepository) GetLikedCats(ctx context.Context, tx *sql.Tx, userId uuid.UUID, page domain.PageRequest) ([]domain.LikedCat, error) {
+	keyset, keysetArgs, direction, err := timeKeyset(page, "l.created_at", "c.id", 2)
+	if err != nil {
+		return nil, err
+	}
+	if keyset != "" {
+		keyset = " AND " + keyset
+	}
+	args := append([]any{userId}, keysetArgs...)
+	args = append(args, page.Limit+1)
+
+	query := `
+		SELECT c.id, c.name, c.race, c.sex,
+			c.age_in_month, c.image_urls, c.description,
+			c.created_at, c.has_matched, c.owned_by_id,
+			c.city,
+			(SELECT COUNT(*) FROM cat_likes WHERE cat_id = c.id),
+			` + catTraitsColumn("c.id") + `,
+			l.created_at
+		FROM cat_likes l
+		JOIN cats c ON c.id = l.cat_id
+		WHERE l.user_id = $1
+			AND c.deleted = false` + keyset + `
+		ORDER BY l.created_at ` + direction + `, c.id ` + direction + `
+		LIMIT $` + strconv.Itoa(len(args)) + `
+	`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cats := []domain.LikedCat{}
+	m := pgtype.NewMap()
+	for rows.Next() {
+		cat := domain.LikedCat{}
+		var city sql.NullString
+
+		err := rows.Scan(
+			&cat.ID,
+			&cat.Name,
+			&cat.Race,
+			&cat.Sex,
+			&cat.AgeInMonth,
+			m.SQLScanner(&cat.ImageUrls),
+			&cat.Description,
+			&cat.CreatedAt,
+			&cat.HasMatched,
+			&cat.OwnedById,
+			&city,
+			&cat.LikeCount,
+			m.SQLScanner(&cat.Traits),
+			&cat.LikedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// only the city, these are other owners' cats
+		if city.Valid {
+			cat.Location = &domain.CatLocation{City: city.String}
+		}
+		cat.Liked = true
+
+		cats = append(cats, cat)
+	}
+
+	return cats, nil
+}
+
+func (c *catLikeRepository) CountLikedCats(ctx context.Context, tx *sql.Tx, userId uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM cat_likes l
+		JOIN cats c ON c.id = l.cat_id
+		WHERE l.user_id = $1
+			AND c.deleted = false
+	`
+
+	var count int
+	err := tx.QueryRowContext(ctx, query, userId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetCatLikers returns who likes the cat, most recent first, one more than
+// page.Limit when there are more. Reading backward from a cursor the page
+// comes in reverse.
+func (c *catLikeRepository) GetCatLikers(ctx context.Context, tx *sql.Tx, catId uuid.UUID, page domain.PageRequest) ([]domain.CatLiker, error) {
+	keyset, keysetArgs, direction, err := timeKeyset(page, "l.created_at", "u.id", 2)
+	if err != nil {
+		return nil, err
+	}
+	if keyset != "" {
+		keyset = " AND " + keyset
+	}
+	args := append([]any{catId}, keysetArgs...)
+	args = append(args, page.Limit+1)
+
+	query := `
+		SELECT u.id, u.name, l.created_at
+		FROM cat_likes l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.cat_id = $1` + keyset + `
+		ORDER BY l.created_at ` + direction + `, u.id ` + direction + `
+		LIMIT $` + strconv.Itoa(len(args)) + `
+	`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	likers := []domain.CatLiker{}
+	for rows.Next() {
+		liker := domain.CatLiker{}
+		err := rows.Scan(&liker.UserID, &liker.Name, &liker.LikedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		likers = append(likers, liker)
+	}
+
+	return likers, nil
+}
+
+// DeleteCatLikesByUserID removes every like of the user, so a deleted
+// account no longer counts.
+func (c *catLikeRepository) DeleteCatLikesByUserID(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	query := `DELETE FROM cat_likes WHERE user_id = $1`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"cats-social/internal/domain"
 	"context"
 	"database/sql"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -12,7 +13,8 @@ import (
 type CatMatchRepository interface {
 	CreateCatMatch(ctx context.Context, tx *sql.Tx, catMatch *domain.CatMatch) (*domain.CatMatch, error)
 	GetCatMatchByID(ctx context.Context, tx *sql.Tx, id string) (*domain.CatMatch, error)
-	GetCatMatchesByIssuerOrReceiverID(ctx context.Context, tx *sql.Tx, userId string) ([]domain.CatMatch, error)
+	GetCatMatchesByIssuerOrReceiverID(ctx context.Context, tx *sql.Tx, userId string, page domain.PageRequest) ([]domain.CatMatch, error)
+	CountCatMatchesByIssuerOrReceiverID(ctx context.Context, tx *sql.Tx, userId string) (int, error)
 	UpdateCatMatchByID(ctx context.Context, tx *sql.Tx, id string, catMatch *domain.CatMatch) error
 	DeleteCatMatchByID(ctx context.Context, tx *sql.Tx, id string) error
 	GetStatusCatMatchByID(ctx context.Context, tx *sql.Tx, id string) (string, error)
@@ -21,6 +23,8 @@ type CatMatchRepository interface {
 	CanDeleteCatMatch(ctx context.Context, tx *sql.Tx, id string, userId string) (bool, error)
 	CheckIfUserIsReceiver(ctx context.Context, tx *sql.Tx, id string, userId string) (bool, error)
 	CheckCatsIsMatching(ctx context.Context, tx *sql.Tx, userCatId uuid.UUID, matchCatId uuid.UUID) (bool, error)
+	WithdrawWaitingCatMatchesByUserID(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+	GetMatchCandidates(ctx context.Context, tx *sql.Tx, cat *domain.Cat, userId uuid.UUID, limit int) ([]domain.Cat, error)
 }
 
 type catMatchRepository struct{}
@@ -94,21 +98,41 @@ func (c *catMatchRepository) GetCatMatchByID(ctx context.Context, tx *sql.Tx, id
 	return &catMatch, nil
 }
 
-func (c *catMatchRepository) GetCatMatchesByIssuerOrReceiverID(ctx context.Context, tx *sql.Tx, userId string) ([]domain.CatMatch, error) {
+// GetCatMatchesByIssuerOrReceiverID returns the waiting matches of a user
+// newest first, one more than page.Limit when there are more. Reading
+// backward from a cursor the page comes oldest first.
+func (c *catMatchRepository) GetCatMatchesByIssuerOrReceiverID(ctx context.Context, tx *sql.Tx, userId string, page domain.PageRequest) ([]domain.CatMatch, error) {
+	keyset, keysetArgs, direction, err := timeKeyset(page, "cm.created_at", "cm.id", 2)
+	if err != nil {
+		return nil, err
+	}
+	if keyset != "" {
+		keyset = " AND " + keyset
+	}
+	args := append([]any{userId}, keysetArgs...)
+	args = append(args, page.Limit+1)
+
 	query := `
 		SELECT	cm.id, cm.created_at, cm.issued_by_id, cm.match_cat_id, cm.user_cat_id, cm.message, cm.status, 
 			u.name as issued_by_name, u.email as issued_by_email, u.created_at as issued_by_created_at, 
 			ca.id as match_cat_id, ca.name as match_cat_name, ca.race as match_cat_race, ca.sex as match_cat_sex, ca.description as match_cat_description, ca.age_in_month as match_cat_age_in_month, ca.image_urls as match_cat_image_urls, ca.has_matched as match_cat_has_matched , ca.created_at as match_cat_created_at,
-			cb.id as user_cat_id, cb.name as user_cat_name, cb.race as user_cat_race, cb.sex as match_cat_sex, cb.description as user_cat_description, cb.age_in_month as user_cat_age_in_month, cb.image_urls as user_cat_image_urls, cb.has_matched as user_cat_has_matched , cb.created_at as user_cat_created_at
+			cb.id as user_cat_id, cb.name as user_cat_name, cb.race as user_cat_race, cb.sex as match_cat_sex, cb.description as user_cat_description, cb.age_in_month as user_cat_age_in_month, cb.image_urls as user_cat_image_urls, cb.has_matched as user_cat_has_matched , cb.created_at as user_cat_created_at,
+			(SELECT COUNT(*) FROM cat_likes WHERE cat_id = ca.id) as match_cat_like_count,
+			EXISTS (SELECT 1 FROM cat_likes WHERE cat_id = ca.id AND user_id = $1) as match_cat_liked,
+			(SELECT COUNT(*) FROM cat_likes WHERE cat_id = cb.id) as user_cat_like_count,
+			EXISTS (SELECT 1 FROM cat_likes WHERE cat_id = cb.id AND user_id = $1) as user_cat_liked,
+			` + catTraitsColumn("ca.id") + ` as match_cat_traits,
+			` + catTraitsColumn("cb.id") + ` as user_cat_traits
 		FROM cat_matches cm
 		INNER JOIN users u ON cm.issued_by_id = u.id
 		INNER JOIN cats ca ON cm.match_cat_id = ca.id
 		INNER JOIN cats cb ON cm.user_cat_id = cb.id
-		WHERE cm.status = 'waiting' AND (ca.owned_by_id = $1 OR cb.owned_by_id = $1)
-		ORDER BY created_at DESC
+		WHERE cm.status = 'waiting' AND (ca.owned_by_id = $1 OR cb.owned_by_id = $1)` + keyset + `
+		ORDER BY cm.created_at ` + direction + `, cm.id ` + direction + `
+		LIMIT $` + strconv.Itoa(len(args)) + `
 	`
 
-	rows, err := tx.QueryContext(ctx, query, userId)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -147,6 +171,12 @@ func (c *catMatchRepository) GetCatMatchesByIssuerOrReceiverID(ctx context.Conte
 			m.SQLScanner(&catMatch.UserCat.ImageUrls),
 			&catMatch.UserCat.HasMatched,
 			&catMatch.UserCat.CreatedAt,
+			&catMatch.MatchCat.LikeCount,
+			&catMatch.MatchCat.Liked,
+			&catMatch.UserCat.LikeCount,
+			&catMatch.UserCat.Liked,
+			m.SQLScanner(&catMatch.MatchCat.Traits),
+			m.SQLScanner(&catMatch.UserCat.Traits),
 		)
 		if err != nil {
 			return nil, err
@@ -158,6 +188,24 @@ func (c *catMatchRepository) GetCatMatchesByIssuerOrReceiverID(ctx context.Conte
 	return catMatches, nil
 }
 
+func (c *catMatchRepository) CountCatMatchesByIssuerOrReceiverID(ctx context.Context, tx *sql.Tx, userId string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM cat_matches cm
+		INNER JOIN cats ca ON cm.match_cat_id = ca.id
+		INNER JOIN cats cb ON cm.user_cat_id = cb.id
+		WHERE cm.status = 'waiting' AND (ca.owned_by_id = $1 OR cb.owned_by_id = $1)
+	`
+
+	var total int
+	err := tx.QueryRowContext(ctx, query, userId).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
 func (c *catMatchRepository) UpdateCatMatchByID(ctx context.Context, tx *sql.Tx, id string, catMatch *domain.CatMatch) error {
 	// currently only update status
 	query := `UPDATE cat_matches SET status = $1 WHERE id = $2`
@@ -298,3 +346,102 @@ func (c *catMatchRepository) CheckCatsIsMatching(ctx context.Context, tx *sql.Tx
 
 	return isMatching, nil
 }
+
+// WithdrawWaitingCatMatchesByUserID removes every waiting request the user
+// issued or that targets one of the user's cats.
+func (c *catMatchRepository) WithdrawWaitingCatMatchesByUserID(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	query := `
+		DELETE FROM cat_matches
+		WHERE status = 'waiting'
+			AND (
+				issued_by_id = $1
+				OR match_cat_id IN (SELECT id FROM cats WHERE owned_by_id = $1)
+				OR user_cat_id IN (SELECT id FROM cats WHERE owned_by_id = $1)
+			)
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetMatchCandidates returns the cats cat could be matched with, by the
+// rules of CreateCatMatch: not deleted, not matched, of the other sex and
+// owned by someone else. Cats with any request from or to one of the
+// user's cats, waiting or rejected, are left out. With a location the
+// nearest come first, then the newest.
+func (c *catMatchRepository) GetMatchCandidates(ctx context.Context, tx *sql.Tx, cat *domain.Cat, userId uuid.UUID, limit int) ([]domain.Cat, error) {
+	args := []any{userId, cat.Sex, limit}
+	order := "c.created_at DESC, c.id"
+	if point := cat.Location.Point(); point != nil {
+		args = append(args, point.Latitude, point.Longitude)
+		order = "c.latitude IS NULL, " + haversine("$4", "$5") + ", " + order
+	}
+
+	query := `
+		SELECT c.id, c.name, c.race, c.sex,
+			c.age_in_month, c.image_urls, c.description,
+			c.created_at, c.has_matched, c.owned_by_id,
+			c.latitude, c.longitude, c.city,
+			(SELECT COUNT(*) FROM cat_likes WHERE cat_id = c.id),
+			EXISTS (SELECT 1 FROM cat_likes WHERE cat_id = c.id AND user_id = $1),
+			` + catTraitsColumn("c.id") + `
+		FROM cats c
+		WHERE c.deleted = false
+			AND c.has_matched = false
+			AND c.sex != $2
+			AND c.owned_by_id != $1
+			AND NOT EXISTS (
+				SELECT 1
+				FROM cat_matches cm
+				WHERE (cm.match_cat_id = c.id AND cm.user_cat_id IN (SELECT id FROM cats WHERE owned_by_id = $1))
+					OR (cm.user_cat_id = c.id AND cm.match_cat_id IN (SELECT id FROM cats WHERE owned_by_id = $1))
+			)
+		ORDER BY ` + order + `
+		LIMIT $3
+	`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []domain.Cat{}
+	m := pgtype.NewMap()
+	for rows.Next() {
+		candidate := domain.Cat{}
+		var latitude, longitude sql.NullFloat64
+		var city sql.NullString
+
+		err := rows.Scan(
+			&candidate.ID,
+			&candidate.Name,
+			&candidate.Race,
+			&candidate.Sex,
+			&candidate.AgeInMonth,
+			m.SQLScanner(&candidate.ImageUrls),
+			&candidate.Description,
+			&candidate.CreatedAt,
+			&candidate.HasMatched,
+			&candidate.OwnedById,
+			&latitude,
+			&longitude,
+			&city,
+			&candidate.LikeCount,
+			&candidate.Liked,
+			m.SQLScanner(&candidate.Traits),
+		)
+		if err != nil {
+			return nil, err
+		}
+		candidate.Location = newCatLocation(latitude, longitude, city)
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type CatPedigreeRepository interface {
+	GetAncestors(db *sql.DB, catId uuid.UUID, generations int) ([]domain.Ancestor, error)
+	GetCommonAncestors(ctx context.Context, tx *sql.Tx, catId uuid.UUID, otherCatId uuid.UUID, generations int) ([]domain.CommonAncestor, error)
+	GetCommonAncestorsWithCats(ctx context.Context, tx *sql.Tx, catId uuid.UUID, otherCatIds []uuid.UUID, generations int) (map[uuid.UUID][]domain.CommonAncestor, error)
+	GetParentSex(ctx context.Context, tx *sql.Tx, catId uuid.UUID) (string, error)
+	IsAncestor(ctx context.Context, tx *sql.Tx, ancestorId uuid.UUID, catId uuid.UUID) (bool, error)
+	IsParentAsOtherSex(ctx context.Context, tx *sql.Tx, catId uuid.UUID, sex string) (bool, error)
+}
+
+type catPedigreeRepository struct{}
+
+func NewCatPedigreeRepository() CatPedigreeRepository {
+	return &catPedigreeRepository{}
+}
+
+// GetAncestors returns the cat and its ancestors up to generations back,
+// each once. Deleted ancestors stay in the lineage, a deleted cat itself has
+// none.
+func (c *catPedigreeRepository) GetAncestors(db *sql.DB, catId uuid.UUID, generations int) ([]domain.Ancestor, error) {
+	query := `
+		WITH RECURSIVE lineage AS (
+			SELECT id, 0 AS generation
+			FROM cats
+			WHERE id = $1
+				AND deleted = false
+			UNION
+			SELECT parent.id, l.generation + 1
+			FROM lineage l
+			JOIN cats c ON c.id = l.id
+			JOIN cats parent ON parent.id IN (c.sire_id, c.dam_id)
+			WHERE l.generation < $2
+		)
+		SELECT id, name, race, sex, age_in_month, sire_id, dam_id
+		FROM cats
+		WHERE id IN (SELECT id FROM lineage)
+	`
+
+	rows, err := db.Query(query, catId, generations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ancestors := []domain.Ancestor{}
+	for rows.Next() {
+		ancestor := domain.Ancestor{}
+		var sireId, damId uuid.NullUUID
+
+		err := rows.Scan(
+			&ancestor.ID,
+			&ancestor.Name,
+			&ancestor.Race,
+			&ancestor.Sex,
+			&ancestor.AgeInMonth,
+			&sireId,
+			&damId,
+		)
+		if err != nil {
+			return nil, err
+		}
+		ancestor.SireID = nullUUIDPtr(sireId)
+		ancestor.DamID = nullUUIDPtr(damId)
+
+		ancestors = append(ancestors, ancestor)
+	}
+
+	return ancestors, nil
+}
+
+// GetCommonAncestors returns the cats found in both lineages within
+// generations, closest first. A cat that is an ancestor of the other is
+// one of them, at generation 0 for itself.
+func (c *catPedigreeRepository) GetCommonAncestors(ctx context.Context, tx *sql.Tx, catId uuid.UUID, otherCatId uuid.UUID, generations int) ([]domain.CommonAncestor, error) {
+	query := `
+		WITH RECURSIVE lineage AS (
+			SELECT id AS cat_id, id, 0 AS generation
+			FROM cats
+			WHERE id IN ($1, $2)
+			UNION
+			SELECT l.cat_id, parent.id, l.generation + 1
+			FROM lineage l
+			JOIN cats c ON c.id = l.id
+			JOIN cats parent ON parent.id IN (c.sire_id, c.dam_id)
+			WHERE l.generation < $3
+		)
+		SELECT c.id, c.name, MIN(a.generation), MIN(b.generation)
+		FROM lineage a
+		JOIN lineage b ON b.id = a.id AND b.cat_id = $2
+		JOIN cats c ON c.id = a.id
+		WHERE a.cat_id = $1
+		GROUP BY c.id
+		ORDER BY MIN(a.generation) + MIN(b.generation), c.name
+	`
+
+	rows, err := tx.QueryContext(ctx, query, catId, otherCatId, generations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ancestors := []domain.CommonAncestor{}
+	for rows.Next() {
+		ancestor := domain.CommonAncestor{}
+		err := rows.Scan(&ancestor.ID, &ancestor.Name, &ancestor.Generation, &ancestor.OtherGeneration)
+		if err != nil {
+			return nil, err
+		}
+
+		ancestors = append(ancestors, ancestor)
+	}
+
+	return ancestors, nil
+}
+
+// GetCommonAncestorsWithCats is GetCommonAncestors for several other cats
+// at once. Only the cats related to catId are in the result.
+func (c *catPedigreeRepository) GetCommonAncestorsWithCats(ctx context.Context, tx *sql.Tx, catId uuid.UUID, otherCatIds []uuid.UUID, generations int) (map[uuid.UUID][]domain.CommonAncestor, error) {
+	ids := make([]string, 0, len(otherCatIds))
+	for _, id := range otherCatIds {
+		ids = append(ids, id.String())
+	}
+
+	query := `
+		WITH RECURSIVE lineage AS (
+			SELECT id AS cat_id, id, 0 AS generation
+			FROM cats
+			WHERE id = $1 OR id = ANY($2::uuid[])
+			UNION
+			SELECT l.cat_id, parent.id, l.generation + 1
+			FROM lineage l
+			JOIN cats c ON c.id = l.id
+			JOIN cats parent ON parent.id IN (c.sire_id, c.dam_id)
+			WHERE l.generation < $3
+		)
+		SELECT b.cat_id, c.id, c.name, MIN(a.generation), MIN(b.generation)
+		FROM lineage a
+		JOIN lineage b ON b.id = a.id AND b.cat_id <> $1
+		JOIN cats c ON c.id = a.id
+		WHERE a.cat_id = $1
+		GROUP BY b.cat_id, c.id
+		ORDER BY b.cat_id, MIN(a.generation) + MIN(b.generation), c.name
+	`
+
+	rows, err := tx.QueryContext(ctx, query, catId, ids, generations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	related := map[uuid.UUID][]domain.CommonAncestor{}
+	for rows.Next() {
+		var otherCatId uuid.UUID
+		ancestor := domain.CommonAncestor{}
+		err := rows.Scan(&otherCatId, &ancestor.ID, &ancestor.Name, &ancestor.Generation, &ancestor.OtherGeneration)
+		if err != nil {
+			return nil, err
+		}
+
+		related[otherCatId] = append(related[otherCatId], ancestor)
+	}
+
+	return related, nil
+}
+
+// GetParentSex returns sql.ErrNoRows when there is no such cat. Deleted
+// cats can still be parents.
+func (c *catPedigreeRepository) GetParentSex(ctx context.Context, tx *sql.Tx, catId uuid.UUID) (string, error) {
+	var sex string
+	err := tx.QueryRowContext(ctx, `SELECT sex FROM cats WHERE id = $1`, catId).Scan(&sex)
+	if err != nil {
+		return "", err
+	}
+
+	return sex, nil
+}
+
+// IsAncestor tells whether ancestorId is in the lineage of catId, however
+// far back.
+func (c *catPedigreeRepository) IsAncestor(ctx context.Context, tx *sql.Tx, ancestorId uuid.UUID, catId uuid.UUID) (bool, error) {
+	query := `
+		WITH RECURSIVE lineage AS (
+			SELECT sire_id, dam_id
+			FROM cats
+			WHERE id = $2
+			UNION
+			SELECT c.sire_id, c.dam_id
+			FROM lineage l
+			JOIN cats c ON c.id IN (l.sire_id, l.dam_id)
+		)
+		SELECT EXISTS (
+			SELECT 1
+			FROM lineage
+			WHERE $1 IN (sire_id, dam_id)
+		)
+	`
+
+	var isAncestor bool
+	err := tx.QueryRowContext(ctx, query, ancestorId, catId).Scan(&isAncestor)
+	if err != nil {
+		return false, err
+	}
+
+	return isAncestor, nil
+}
+
+// IsParentAsOtherSex tells whether the cat is the sire of a cat while sex is
+// not male, or the dam of one while sex is not female.
+func (c *catPedigreeRepository) IsParentAsOtherSex(ctx context.Context, tx *sql.Tx, catId uuid.UUID, sex string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM cats
+			WHERE (sire_id = $1 AND $2 <> 'male')
+				OR (dam_id = $1 AND $2 <> 'female')
+		)
+	`
+
+	var mismatch bool
+	err := tx.QueryRowContext(ctx, query, catId, sex).Scan(&mismatch)
+	if err != nil {
+		return false, err
+	}
+
+	return mismatch, nil
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+
+	return &id.UUID
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type CatTraitRepository interface {
+	GetTraitCategories(db *sql.DB) ([]domain.TraitCategory, error)
+	SetCatTraits(ctx context.Context, tx *sql.Tx, catId uuid.UUID, traitIds []string) error
+}
+
+type catTraitRepository struct{}
+
+func NewCatTraitRepository() CatTraitRepository {
+	return &catTraitRepository{}
+}
+
+// GetTraitCategories returns the whole vocabulary, categories and their
+// traits in order.
+func (c *catTraitRepository) GetTraitCategories(db *sql.DB) ([]domain.TraitCategory, error) {
+	query := `
+		SELECT tc.id, tc.name, tc.multiple, t.id, t.name
+		FROM trait_categories tc
+		JOIN traits t ON t.category_id = tc.id
+		ORDER BY tc.position, t.position
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []domain.TraitCategory{}
+	for rows.Next() {
+		category := domain.TraitCategory{}
+		trait := domain.Trait{}
+		err := rows.Scan(&category.ID, &category.Name, &category.Multiple, &trait.ID, &trait.Name)
+		if err != nil {
+			return nil, err
+		}
+		trait.CategoryID = category.ID
+
+		if n := len(categories); n > 0 && categories[n-1].ID == category.ID {
+			categories[n-1].Traits = append(categories[n-1].Traits, trait)
+			continue
+		}
+		category.Traits = []domain.Trait{trait}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+// SetCatTraits replaces the traits of a cat.
+func (c *catTraitRepository) SetCatTraits(ctx context.Context, tx *sql.Tx, catId uuid.UUID, traitIds []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM cat_traits WHERE cat_id = $1`, catId)
+	if err != nil {
+		return err
+	}
+
+	if len(traitIds) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO cat_traits (cat_id, trait_id)
+		SELECT $1, unnest($2::varchar[])
+	`
+
+	_, err = tx.ExecContext(ctx, query, catId, traitIds)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// catTraitsColumn selects the trait ids of the cat whose id is in
+// catIdColumn, in vocabulary order.
+func catTraitsColumn(catIdColumn string) string {
+	return fmt.Sprintf(`ARRAY(
+				SELECT ct.trait_id
+				FROM cat_traits ct
+				JOIN traits t ON t.id = ct.trait_id
+				WHERE ct.cat_id = %s
+				ORDER BY t.position
+			)`, catIdColumn)
+}
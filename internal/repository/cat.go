@@ -5,20 +5,24 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"net/url"
+	"html"
 	"regexp"
-	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type CatRepository interface {
-	CreateCat(db *sql.DB, cat *domain.Cat) error
-	GetAllCats(db *sql.DB, user *domain.User, queryParams url.Values) ([]domain.Cat, error)
-	UpdateCat(db *sql.DB, cat *domain.Cat) error
+	CreateCat(ctx context.Context, tx *sql.Tx, cat *domain.Cat) error
+	GetAllCats(db *sql.DB, user *domain.User, filter *domain.CatFilter) ([]domain.Cat, error)
+	CountCats(db *sql.DB, user *domain.User, filter *domain.CatFilter) (int, error)
+	GetCatDetail(db *sql.DB, catId uuid.UUID, userId uuid.UUID) (*domain.CatDetail, error)
+	UpdateCat(ctx context.Context, tx *sql.Tx, cat *domain.Cat) error
 	DeleteCat(db *sql.DB, catId uuid.UUID) error
+	DeleteCatsByOwnerId(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+	GetCatIdsByOwnerId(db *sql.DB, userId uuid.UUID) ([]uuid.UUID, error)
 	CheckCatExists(db *sql.DB, catId uuid.UUID, userId uuid.UUID) (bool, error)
 	CheckEditableSex(db *sql.DB, cat *domain.Cat) (bool, error)
 	CheckOwnerCat(ctx context.Context, tx *sql.Tx, catId uuid.UUID, userId uuid.UUID) (bool, error)
@@ -34,11 +38,12 @@ func NewCatRepository() CatRepository {
 	return &catRepository{}
 }
 
-func (c *catRepository) CreateCat(db *sql.DB, catBody *domain.Cat) error {
-	query := `INSERT INTO cats (id, created_at, name, race, sex, age_in_month, description, image_urls, owned_by_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+func (c *catRepository) CreateCat(ctx context.Context, tx *sql.Tx, catBody *domain.Cat) error {
+	query := `INSERT INTO cats (id, created_at, name, race, sex, age_in_month, description, image_urls, owned_by_id, latitude, longitude, city, sire_id, dam_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		`
-	_, err := db.Exec(query, catBody.ID, catBody.CreatedAt, catBody.Name, catBody.Race, catBody.Sex, catBody.AgeInMonth, catBody.Description, catBody.ImageUrls, catBody.OwnedById)
+	latitude, longitude, city := catLocationArgs(catBody.Location)
+	_, err := tx.ExecContext(ctx, query, catBody.ID, catBody.CreatedAt, catBody.Name, catBody.Race, catBody.Sex, catBody.AgeInMonth, catBody.Description, catBody.ImageUrls, catBody.OwnedById, latitude, longitude, city, catBody.SireID, catBody.DamID)
 	if err != nil {
 		return err
 	}
@@ -46,22 +51,81 @@ func (c *catRepository) CreateCat(db *sql.DB, catBody *domain.Cat) error {
 	return nil
 }
 
-func (c *catRepository) GetAllCats(db *sql.DB, user *domain.User, queryParams url.Values) ([]domain.Cat, error) {
-	query := `
+// GetAllCats reads a page of cats, in the order it is shown, as filter
+// says. It returns one cat more than filter.Limit when there are more, and
+// reads backward from a backward cursor, so the page comes reversed.
+func (c *catRepository) GetAllCats(db *sql.DB, user *domain.User, filter *domain.CatFilter) ([]domain.Cat, error) {
+	whereClause, args, search, distance := catFilterClauses(filter, user.Id)
+	args = append(args, user.Id)
+
+	query := fmt.Sprintf(`
 		SELECT id, name, race, sex,
 			age_in_month, image_urls, description,
-			created_at, has_matched
+			created_at, has_matched, owned_by_id,
+			latitude, longitude, city,
+			(SELECT COUNT(*) FROM cat_likes WHERE cat_id = cats.id),
+			EXISTS (SELECT 1 FROM cat_likes WHERE cat_id = cats.id AND user_id = $%d),
+			%s`, len(args), catTraitsColumn("cats.id"))
+
+	sortColumn := catSortColumns[filter.Sort]
+	if distance != "" {
+		// shown to 100 m and sorted as shown, so cursors round-trip
+		distance = fmt.Sprintf("round((%s)::numeric, 1)::float8", distance)
+		if filter.Sort == domain.CatSortDistance {
+			sortColumn = distance
+		}
+
+		query += ",\n\t\t\t" + distance
+	}
+	if search != nil {
+		tsquery := fmt.Sprintf("to_tsquery('simple', $%d)", search.tsqueryArg)
+		rank := fmt.Sprintf("(ts_rank(search_vector, %s) + word_similarity($%d, name))", tsquery, search.textArg)
+		if filter.Sort == domain.CatSortRelevance {
+			sortColumn = rank
+		}
+
+		query += fmt.Sprintf(`,
+			%[2]s,
+			ts_headline('simple', name, %[1]s, '%[3]s, HighlightAll=true'),
+			ts_headline('simple', description, %[1]s, '%[3]s, MaxFragments=2, MaxWords=20, MinWords=5')`,
+			tsquery, rank, highlightOptions)
+	}
+
+	query += `
 		FROM cats
 		WHERE deleted = false
 		`
 
-	whereClause, limitOffsetClause, args := validateGetAllCatsQueryParams(queryParams, user.Id.String())
+	desc := filter.SortDesc
+	if filter.Cursor != nil {
+		value, err := domain.CatCursorValue(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if filter.Cursor.Backward {
+			desc = !desc
+		}
+		operator := ">"
+		if desc {
+			operator = "<"
+		}
+
+		args = append(args, value, filter.Cursor.ID)
+		whereClause = append(whereClause, fmt.Sprintf("(%s, id) %s ($%d, $%d)", sortColumn, operator, len(args)-1, len(args)))
+	}
 
 	if len(whereClause) > 0 {
 		query += "AND " + strings.Join(whereClause, " AND ")
 	}
-	query += "\n" + "ORDER BY created_at DESC"
-	query += "\n" + strings.Join(limitOffsetClause, " ")
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	query += fmt.Sprintf("\nORDER BY %[1]s %[2]s, id %[2]s", sortColumn, direction)
+	query += fmt.Sprintf("\nLIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit+1, filter.Offset)
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
@@ -74,19 +138,125 @@ func (c *catRepository) GetAllCats(db *sql.DB, user *domain.User, queryParams ur
 
 	for rows.Next() {
 		cat := domain.Cat{}
+		var latitude, longitude sql.NullFloat64
+		var city sql.NullString
+		dest := []any{&cat.ID, &cat.Name, &cat.Race, &cat.Sex, &cat.AgeInMonth, m.SQLScanner(&cat.ImageUrls), &cat.Description, &cat.CreatedAt, &cat.HasMatched, &cat.OwnedById, &latitude, &longitude, &city, &cat.LikeCount, &cat.Liked, m.SQLScanner(&cat.Traits)}
 
-		err = rows.Scan(&cat.ID, &cat.Name, &cat.Race, &cat.Sex, &cat.AgeInMonth, m.SQLScanner(&cat.ImageUrls), &cat.Description, &cat.CreatedAt, &cat.HasMatched)
+		if distance != "" {
+			dest = append(dest, &cat.DistanceKm)
+		}
+		if search != nil {
+			cat.Search = &domain.CatSearchMatch{}
+			dest = append(dest, &cat.Search.Rank, &cat.Search.NameHighlight, &cat.Search.DescriptionHighlight)
+		}
+
+		err = rows.Scan(dest...)
 		if err != nil {
 			return nil, err
 		}
 
+		cat.Location = newCatLocation(latitude, longitude, city)
+		if search != nil {
+			cat.Search.NameHighlight = escapeHighlight(cat.Search.NameHighlight)
+			cat.Search.DescriptionHighlight = escapeHighlight(cat.Search.DescriptionHighlight)
+		}
+
 		cats = append(cats, cat)
 	}
 
 	return cats, nil
 }
 
-func (c *catRepository) UpdateCat(db *sql.DB, cat *domain.Cat) error {
+// CountCats counts every cat matching filter, ignoring its paging.
+func (c *catRepository) CountCats(db *sql.DB, user *domain.User, filter *domain.CatFilter) (int, error) {
+	whereClause, args, _, _ := catFilterClauses(filter, user.Id)
+
+	query := `SELECT COUNT(*) FROM cats WHERE deleted = false `
+	if len(whereClause) > 0 {
+		query += "AND " + strings.Join(whereClause, " AND ")
+	}
+
+	var total int
+	err := db.QueryRow(query, args...).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// GetCatDetail returns sql.ErrNoRows when the cat does not exist or is
+// deleted.
+func (c *catRepository) GetCatDetail(db *sql.DB, catId uuid.UUID, userId uuid.UUID) (*domain.CatDetail, error) {
+	query := `
+		SELECT c.id, c.name, c.race, c.sex,
+			c.age_in_month, c.image_urls, c.description,
+			c.created_at, c.has_matched, c.owned_by_id,
+			c.latitude, c.longitude, c.city,
+			c.sire_id, c.dam_id,
+			(SELECT COUNT(*) FROM cat_likes WHERE cat_id = c.id),
+			EXISTS (SELECT 1 FROM cat_likes WHERE cat_id = c.id AND user_id = $2),
+			` + catTraitsColumn("c.id") + `,
+			u.name,
+			(SELECT COUNT(*) FROM cat_matches WHERE user_cat_id = c.id AND status = 'waiting'),
+			(SELECT COUNT(*) FROM cat_matches WHERE match_cat_id = c.id AND status = 'waiting'),
+			m.id,
+			CASE WHEN m.match_cat_id = c.id THEN m.user_cat_id ELSE m.match_cat_id END
+		FROM cats c
+		JOIN users u ON u.id = c.owned_by_id
+		LEFT JOIN LATERAL (
+			SELECT id, match_cat_id, user_cat_id
+			FROM cat_matches
+			WHERE status = 'approved'
+				AND (match_cat_id = c.id OR user_cat_id = c.id)
+			ORDER BY created_at DESC
+			LIMIT 1
+		) m ON true
+		WHERE c.id = $1
+			AND c.deleted = false
+	`
+	detail := domain.CatDetail{}
+	var latitude, longitude sql.NullFloat64
+	var city sql.NullString
+	var sireId, damId uuid.NullUUID
+	m := pgtype.NewMap()
+
+	err := db.QueryRow(query, catId, userId).Scan(
+		&detail.ID,
+		&detail.Name,
+		&detail.Race,
+		&detail.Sex,
+		&detail.AgeInMonth,
+		m.SQLScanner(&detail.ImageUrls),
+		&detail.Description,
+		&detail.CreatedAt,
+		&detail.HasMatched,
+		&detail.OwnedById,
+		&latitude,
+		&longitude,
+		&city,
+		&sireId,
+		&damId,
+		&detail.LikeCount,
+		&detail.Liked,
+		m.SQLScanner(&detail.Traits),
+		&detail.OwnerName,
+		&detail.SentRequests,
+		&detail.ReceivedRequests,
+		&detail.MatchId,
+		&detail.MatchedCatId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	detail.Location = newCatLocation(latitude, longitude, city)
+	detail.SireID = nullUUIDPtr(sireId)
+	detail.DamID = nullUUIDPtr(damId)
+
+	return &detail, nil
+}
+
+func (c *catRepository) UpdateCat(ctx context.Context, tx *sql.Tx, cat *domain.Cat) error {
 	query := `
 		UPDATE cats
 		SET name = $2,
@@ -94,10 +264,15 @@ func (c *catRepository) UpdateCat(db *sql.DB, cat *domain.Cat) error {
 			sex = $4,
 			age_in_month = $5,
 			description = $6,
-			image_urls = $7
+			latitude = $7,
+			longitude = $8,
+			city = $9,
+			sire_id = $10,
+			dam_id = $11
 		WHERE id = $1
 	`
-	_, err := db.Exec(query, cat.ID, cat.Name, cat.Race, cat.Sex, cat.AgeInMonth, cat.Description, cat.ImageUrls)
+	latitude, longitude, city := catLocationArgs(cat.Location)
+	_, err := tx.ExecContext(ctx, query, cat.ID, cat.Name, cat.Race, cat.Sex, cat.AgeInMonth, cat.Description, latitude, longitude, city, cat.SireID, cat.DamID)
 	if err != nil {
 		return err
 	}
@@ -120,6 +295,51 @@ func (c *catRepository) DeleteCat(db *sql.DB, catId uuid.UUID) error {
 	return nil
 }
 
+// DeleteCatsByOwnerId soft-deletes every cat of the user.
+func (c *catRepository) DeleteCatsByOwnerId(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	query := `
+		UPDATE cats
+		SET deleted = true
+		WHERE owned_by_id = $1
+			AND deleted = false
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *catRepository) GetCatIdsByOwnerId(db *sql.DB, userId uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM cats
+		WHERE owned_by_id = $1
+			AND deleted = false
+	`
+
+	rows, err := db.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	catIds := []uuid.UUID{}
+	for rows.Next() {
+		var catId uuid.UUID
+		err = rows.Scan(&catId)
+		if err != nil {
+			return nil, err
+		}
+
+		catIds = append(catIds, catId)
+	}
+
+	return catIds, nil
+}
+
 func (c *catRepository) CheckCatExists(db *sql.DB, catId uuid.UUID, userId uuid.UUID) (bool, error) {
 	queryCheckCatId := `
 		SELECT EXISTS (
@@ -255,88 +475,196 @@ func (c *catRepository) CheckBothCatExists(ctx context.Context, tx *sql.Tx, cat1
 	return bothExists, nil
 }
 
-func validateGetAllCatsQueryParams(queryParams url.Values, userId string) ([]string, []string, []any) {
-	var limitOffsetClause []string
+// catFilterClauses turns the filter into WHERE conditions and their args.
+// With filter.Near it also returns the expression of the distance in km.
+func catFilterClauses(filter *domain.CatFilter, userId uuid.UUID) ([]string, []any, *catSearch, string) {
 	var whereClause []string
 	var args []any
+	var search *catSearch
+	var distance string
 
-	for key, value := range queryParams {
-		undefinedParam := slices.Contains(domain.CatQueryParams, key) != true
-		limitOffset := key == "limit" || key == "offset"
-		emptyValue := len(value[0]) < 1
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
 
-		if limitOffset {
-			limitOffsetClause = append(limitOffsetClause, fmt.Sprintf("%s $%d", key, len(args)+1))
+	if filter.ID != nil {
+		whereClause = append(whereClause, "id = "+arg(*filter.ID))
+	}
+	if len(filter.Races) > 0 {
+		whereClause = append(whereClause, "race = ANY("+arg(filter.Races)+")")
+	}
+	if len(filter.Sexes) > 0 {
+		whereClause = append(whereClause, "sex = ANY("+arg(filter.Sexes)+")")
+	}
+	if filter.HasMatched != nil {
+		whereClause = append(whereClause, "has_matched = "+arg(*filter.HasMatched))
+	}
+	for _, age := range filter.Ages {
+		// the operator is one of a fixed set, checked by the handler
+		whereClause = append(whereClause, "age_in_month "+age.Operator+" "+arg(age.Value))
+	}
+	if filter.Owned != nil {
+		if *filter.Owned {
+			whereClause = append(whereClause, "owned_by_id = "+arg(userId))
+		} else {
+			whereClause = append(whereClause, "owned_by_id != "+arg(userId))
+		}
+	}
+	if filter.Liked != nil {
+		liked := "EXISTS (SELECT 1 FROM cat_likes WHERE cat_id = cats.id AND user_id = " + arg(userId) + ")"
+		if !*filter.Liked {
+			liked = "NOT " + liked
+		}
+		whereClause = append(whereClause, liked)
+	}
+	if len(filter.Traits) > 0 {
+		if filter.TraitsMatchAny {
+			whereClause = append(whereClause, "EXISTS (SELECT 1 FROM cat_traits WHERE cat_id = cats.id AND trait_id = ANY("+arg(filter.Traits)+"))")
+		} else {
+			// the traits are distinct, so having all of them is having as many
+			whereClause = append(whereClause, "(SELECT COUNT(*) FROM cat_traits WHERE cat_id = cats.id AND trait_id = ANY("+arg(filter.Traits)+")) = "+arg(len(filter.Traits)))
+		}
+	}
+	if filter.CreatedAfter != nil {
+		whereClause = append(whereClause, "created_at > "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		whereClause = append(whereClause, "created_at < "+arg(*filter.CreatedBefore))
+	}
 
-			if key == "limit" && emptyValue {
-				value[0] = "5"
-			}
-			if key == "offset" && emptyValue {
-				value[0] = "0"
-			}
+	if terms := searchTerms(filter.Search); len(terms) > 0 {
+		search = &catSearch{tsqueryArg: len(args) + 1, textArg: len(args) + 2}
+		// prefix matches on the words, trigrams catch typos
+		whereClause = append(whereClause, fmt.Sprintf(
+			"(search_vector @@ to_tsquery('simple', $%[1]d) OR $%[2]d <%% name OR $%[2]d <%% description)",
+			search.tsqueryArg, search.textArg,
+		))
+		args = append(args, prefixTsquery(terms), strings.Join(terms, " "))
+	}
 
-			args = append(args, value[0])
-			continue
+	if filter.Near != nil {
+		// the box can use the index, the exact distance is checked after
+		box := filter.Near.BoundingBox(filter.RadiusKm)
+		whereClause = append(whereClause, "latitude BETWEEN "+arg(box.MinLatitude)+" AND "+arg(box.MaxLatitude))
+		switch {
+		case box.AllLongitudes:
+		case box.MinLongitude <= box.MaxLongitude:
+			whereClause = append(whereClause, "longitude BETWEEN "+arg(box.MinLongitude)+" AND "+arg(box.MaxLongitude))
+		default:
+			// across the antimeridian
+			whereClause = append(whereClause, "(longitude >= "+arg(box.MinLongitude)+" OR longitude <= "+arg(box.MaxLongitude)+")")
 		}
 
-		qParamsToSkip := undefinedParam || limitOffset || emptyValue
-		if qParamsToSkip {
-			continue
-		}
+		distance = haversine(arg(filter.Near.Latitude), arg(filter.Near.Longitude))
+		whereClause = append(whereClause, distance+" <= "+arg(filter.RadiusKm))
+	}
 
-		if key == "id" {
-			_, err := uuid.Parse(value[0])
-			if err != nil {
-				continue
-			}
-		}
+	return whereClause, args, search, distance
+}
 
-		if key == "hasMatched" {
-			key = "has_matched"
-		}
+// haversine is the great circle distance in km from the cat to the point
+// at the given args.
+func haversine(latitude string, longitude string) string {
+	return fmt.Sprintf(
+		"(%[3]g * 2 * asin(sqrt(LEAST(1, power(sin(radians(latitude - %[1]s) / 2), 2) + "+
+			"cos(radians(%[1]s)) * cos(radians(latitude)) * power(sin(radians(longitude - %[2]s) / 2), 2)))))",
+		latitude, longitude, domain.EarthRadiusKm,
+	)
+}
 
-		if key == "ageInMonth" {
-			key = "age_in_month"
+// catLocationArgs are the column values of a location, NULL without one.
+func catLocationArgs(location *domain.CatLocation) (any, any, any) {
+	if location == nil {
+		return nil, nil, nil
+	}
 
-			// regex to extract operator (>,=,<) and number
-			extractOperatorAndNumber := regexp.MustCompile(`([>=<])(\d+)`)
-			matches := extractOperatorAndNumber.FindStringSubmatch(value[0])
-			if len(matches) != 3 {
-				continue
-			}
+	var city any
+	if location.City != "" {
+		city = location.City
+	}
 
-			opr := matches[1]
-			val := matches[2]
+	return location.Latitude, location.Longitude, city
+}
 
-			whereClause = append(whereClause, fmt.Sprintf("%s %s $%d", key, opr, len(args)+1))
-			args = append(args, val)
+func newCatLocation(latitude sql.NullFloat64, longitude sql.NullFloat64, city sql.NullString) *domain.CatLocation {
+	if !latitude.Valid && !city.Valid {
+		return nil
+	}
 
-			continue
-		}
+	location := &domain.CatLocation{City: city.String}
+	if latitude.Valid && longitude.Valid {
+		location.Latitude = &latitude.Float64
+		location.Longitude = &longitude.Float64
+	}
 
-		if key == "owned" {
-			if value[0] != "true" && value[0] != "false" {
-				continue
-			}
+	return location
+}
 
-			key = "owned_by_id"
+// catSortColumns maps the sorts of the API to columns, relevance and distance
+// are worked out by the query.
+var catSortColumns = map[string]string{
+	domain.CatSortName:       "name",
+	domain.CatSortAgeInMonth: "age_in_month",
+	domain.CatSortCreatedAt:  "created_at",
+	domain.CatSortDistance:   "created_at",
+	domain.CatSortRelevance:  "created_at",
+}
 
-			if value[0] == "false" {
-				whereClause = append(whereClause, fmt.Sprintf("%s != $%d", key, len(args)+1))
-				args = append(args, userId)
-				continue
-			}
+// catSearch is where the search terms are among the query args.
+type catSearch struct {
+	tsqueryArg int
+	textArg    int
+}
 
-			value[0] = userId
-		}
+const (
+	maxSearchTerms = 8
+
+	// ts_headline marks matches with characters HTML escaping leaves alone,
+	// they are swapped for tags once the text is escaped
+	highlightStart   = "\u27e6"
+	highlightStop    = "\u27e7"
+	highlightOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+)
 
-		if key == "search" {
-			key = "name"
+var searchTermPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// searchTerms splits a search into lower case words, dropping everything
+// to_tsquery would treat as syntax. Single letters, like the s of "Mitt's",
+// are dropped too unless there is nothing else.
+func searchTerms(search string) []string {
+	words := searchTermPattern.FindAllString(strings.ToLower(search), -1)
+
+	terms := []string{}
+	for _, word := range words {
+		if utf8.RuneCountInString(word) > 1 {
+			terms = append(terms, word)
 		}
+	}
+	if len(terms) == 0 {
+		terms = words
+	}
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+
+	return terms
+}
 
-		whereClause = append(whereClause, fmt.Sprintf("%s = $%d", key, len(args)+1))
-		args = append(args, value[0])
+// prefixTsquery matches every term as a word prefix, "mitt" finds "Mittens".
+func prefixTsquery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		parts = append(parts, term+":*")
 	}
 
-	return whereClause, limitOffsetClause, args
+	return strings.Join(parts, " & ")
+}
+
+func escapeHighlight(highlight string) string {
+	highlight = html.EscapeString(highlight)
+	highlight = strings.ReplaceAll(highlight, highlightStart, "<mark>")
+	highlight = strings.ReplaceAll(highlight, highlightStop, "</mark>")
+
+	return highlight
 }
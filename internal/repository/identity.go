@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type IdentityRepository interface {
+	CreateLoginState(db *sql.DB, state *domain.OIDCLoginState) error
+	ConsumeLoginState(db *sql.DB, stateHash string) (*domain.OIDCLoginState, error)
+	GetUserIdByIdentity(db *sql.DB, issuer string, subject string) (uuid.UUID, error)
+	CreateIdentity(ctx context.Context, tx *sql.Tx, identity *domain.UserIdentity) error
+	DeleteUserIdentities(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+}
+
+type identityRepository struct{}
+
+func NewIdentityRepository() IdentityRepository {
+	return &identityRepository{}
+}
+
+func (i *identityRepository) CreateLoginState(db *sql.DB, state *domain.OIDCLoginState) error {
+	query := `INSERT INTO oidc_login_states (state_hash, code_verifier, nonce, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := db.Exec(query, state.StateHash, state.CodeVerifier, state.Nonce, state.CreatedAt, state.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	// expired states are never consumed, clean them up on the way
+	_, err = db.Exec(`DELETE FROM oidc_login_states WHERE expires_at < now()`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeLoginState deletes and returns an unexpired state so that it can be
+// used only once. It returns sql.ErrNoRows when no such state exists.
+func (i *identityRepository) ConsumeLoginState(db *sql.DB, stateHash string) (*domain.OIDCLoginState, error) {
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1
+			AND expires_at > now()
+		RETURNING state_hash, code_verifier, nonce, created_at, expires_at
+	`
+	state := domain.OIDCLoginState{}
+
+	err := db.QueryRow(query, stateHash).Scan(&state.StateHash, &state.CodeVerifier, &state.Nonce, &state.CreatedAt, &state.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func (i *identityRepository) GetUserIdByIdentity(db *sql.DB, issuer string, subject string) (uuid.UUID, error) {
+	query := `SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2`
+
+	var userId uuid.UUID
+	err := db.QueryRow(query, issuer, subject).Scan(&userId)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return userId, nil
+}
+
+func (i *identityRepository) CreateIdentity(ctx context.Context, tx *sql.Tx, identity *domain.UserIdentity) error {
+	query := `INSERT INTO user_identities (id, user_id, issuer, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := tx.ExecContext(ctx, query, identity.ID, identity.UserID, identity.Issuer, identity.Subject, identity.Email, identity.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUserIdentities unlinks every provider account of the user, so they
+// can sign in to a new account again.
+func (i *identityRepository) DeleteUserIdentities(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	query := `DELETE FROM user_identities WHERE user_id = $1`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"fmt"
+)
+
+// timeKeyset pages a listing sorted newest first on (timeColumn, idColumn).
+// It returns the condition of the cursor, if there is one, with its args
+// numbered from firstArg, and the direction to sort in. Reading backward the
+// rows come oldest first.
+func timeKeyset(page domain.PageRequest, timeColumn string, idColumn string, firstArg int) (string, []any, string, error) {
+	if page.Cursor == nil {
+		return "", nil, "DESC", nil
+	}
+
+	t, err := page.Cursor.Time()
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	operator, direction := "<", "DESC"
+	if page.Cursor.Backward {
+		operator, direction = ">", "ASC"
+	}
+	condition := fmt.Sprintf("(%s, %s) %s ($%d, $%d)", timeColumn, idColumn, operator, firstArg, firstArg+1)
+
+	return condition, []any{t, page.Cursor.ID}, direction, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type PasswordResetRepository interface {
+	CreateToken(ctx context.Context, tx *sql.Tx, token *domain.PasswordResetToken) error
+	InvalidateUserTokens(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+	ConsumeToken(ctx context.Context, tx *sql.Tx, tokenHash string) (uuid.UUID, error)
+}
+
+type passwordResetRepository struct{}
+
+func NewPasswordResetRepository() PasswordResetRepository {
+	return &passwordResetRepository{}
+}
+
+func (p *passwordResetRepository) CreateToken(ctx context.Context, tx *sql.Tx, token *domain.PasswordResetToken) error {
+	query := `INSERT INTO password_reset_tokens (id, user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := tx.ExecContext(ctx, query, token.ID, token.UserID, token.TokenHash, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *passwordResetRepository) InvalidateUserTokens(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = now()
+		WHERE user_id = $1
+			AND used_at IS NULL
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeToken marks an unused, unexpired token as used and returns the id of
+// the user it belongs to. It returns sql.ErrNoRows when no such token exists.
+func (p *passwordResetRepository) ConsumeToken(ctx context.Context, tx *sql.Tx, tokenHash string) (uuid.UUID, error) {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = now()
+		WHERE token_hash = $1
+			AND used_at IS NULL
+			AND expires_at > now()
+		RETURNING user_id
+	`
+
+	var userId uuid.UUID
+	err := tx.QueryRowContext(ctx, query, tokenHash).Scan(&userId)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return userId, nil
+}
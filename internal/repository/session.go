@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SessionRepository interface {
+	CreateSession(db *sql.DB, session *domain.Session) error
+	GetByRefreshTokenHash(db *sql.DB, hash string) (*domain.Session, error)
+	RotateRefreshToken(db *sql.DB, id uuid.UUID, oldHash string, newHash string, expiresAt time.Time) (bool, error)
+	GetById(db *sql.DB, id uuid.UUID) (*domain.Session, error)
+	GetActiveSessionsByUserId(db *sql.DB, userId uuid.UUID) ([]domain.Session, error)
+	TouchSession(db *sql.DB, id uuid.UUID, ip string) error
+	RevokeSession(db *sql.DB, id uuid.UUID) error
+	RevokeUserSession(db *sql.DB, id uuid.UUID, userId uuid.UUID) (bool, error)
+	RevokeUserSessions(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+	RevokeOtherUserSessions(ctx context.Context, tx *sql.Tx, userId uuid.UUID, keepId uuid.UUID) error
+}
+
+type sessionRepository struct{}
+
+func NewSessionPg() SessionRepository {
+	return &sessionRepository{}
+}
+
+func (s *sessionRepository) CreateSession(db *sql.DB, session *domain.Session) error {
+	query := `INSERT INTO sessions (id, user_id, refresh_token_hash, created_at, expires_at, user_agent, device_name, ip, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := db.Exec(
+		query,
+		session.ID,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.CreatedAt,
+		session.ExpiresAt,
+		session.UserAgent,
+		session.DeviceName,
+		session.IP,
+		session.LastSeenAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRepository) GetByRefreshTokenHash(db *sql.DB, hash string) (*domain.Session, error) {
+	query := `SELECT id, user_id, refresh_token_hash, created_at, expires_at, revoked_at
+		FROM sessions WHERE refresh_token_hash = $1
+	`
+	session := domain.Session{}
+
+	err := db.QueryRow(query, hash).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.RefreshTokenHash,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// RotateRefreshToken swaps the refresh token of an active session. It only
+// succeeds when oldHash is still the current one, so a refresh token can be
+// exchanged at most once.
+func (s *sessionRepository) RotateRefreshToken(db *sql.DB, id uuid.UUID, oldHash string, newHash string, expiresAt time.Time) (bool, error) {
+	query := `
+		UPDATE sessions
+		SET refresh_token_hash = $3,
+			expires_at = $4,
+			last_seen_at = now()
+		WHERE id = $1
+			AND refresh_token_hash = $2
+			AND revoked_at IS NULL
+	`
+
+	res, err := db.Exec(query, id, oldHash, newHash, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (s *sessionRepository) GetById(db *sql.DB, id uuid.UUID) (*domain.Session, error) {
+	query := `SELECT id, user_id, created_at, expires_at, revoked_at, user_agent, device_name, ip, last_seen_at
+		FROM sessions WHERE id = $1
+	`
+	session := domain.Session{}
+
+	err := db.QueryRow(query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.UserAgent,
+		&session.DeviceName,
+		&session.IP,
+		&session.LastSeenAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (s *sessionRepository) GetActiveSessionsByUserId(db *sql.DB, userId uuid.UUID) ([]domain.Session, error) {
+	query := `
+		SELECT id, user_id, created_at, expires_at, revoked_at, user_agent, device_name, ip, last_seen_at
+		FROM sessions
+		WHERE user_id = $1
+			AND revoked_at IS NULL
+			AND expires_at > now()
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := db.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []domain.Session{}
+	for rows.Next() {
+		session := domain.Session{}
+
+		err = rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.CreatedAt,
+			&session.ExpiresAt,
+			&session.RevokedAt,
+			&session.UserAgent,
+			&session.DeviceName,
+			&session.IP,
+			&session.LastSeenAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+func (s *sessionRepository) TouchSession(db *sql.DB, id uuid.UUID, ip string) error {
+	query := `UPDATE sessions SET last_seen_at = now(), ip = $2 WHERE id = $1`
+
+	_, err := db.Exec(query, id, ip)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRepository) RevokeSession(db *sql.DB, id uuid.UUID) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE id = $1
+			AND revoked_at IS NULL
+	`
+
+	_, err := db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeUserSession revokes one active session of the user. It reports false
+// when the user has no such session.
+func (s *sessionRepository) RevokeUserSession(db *sql.DB, id uuid.UUID, userId uuid.UUID) (bool, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE id = $1
+			AND user_id = $2
+			AND revoked_at IS NULL
+			AND expires_at > now()
+	`
+
+	res, err := db.Exec(query, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (s *sessionRepository) RevokeUserSessions(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE user_id = $1
+			AND revoked_at IS NULL
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRepository) RevokeOtherUserSessions(ctx context.Context, tx *sql.Tx, userId uuid.UUID, keepId uuid.UUID) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE user_id = $1
+			AND id != $2
+			AND revoked_at IS NULL
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId, keepId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"cats-social/internal/domain"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type TwoFactorRepository interface {
+	GetTwoFactor(db *sql.DB, userId uuid.UUID) (*domain.TwoFactor, error)
+	SetPendingSecret(db *sql.DB, userId uuid.UUID, secret string) (bool, error)
+	UseTimeStep(db *sql.DB, userId uuid.UUID, step int64) (bool, error)
+	EnableTwoFactor(ctx context.Context, tx *sql.Tx, userId uuid.UUID, step int64) error
+	DisableTwoFactor(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userId uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(db *sql.DB, userId uuid.UUID, codeHash string) (bool, error)
+}
+
+type twoFactorRepository struct{}
+
+func NewTwoFactorRepository() TwoFactorRepository {
+	return &twoFactorRepository{}
+}
+
+func (t *twoFactorRepository) GetTwoFactor(db *sql.DB, userId uuid.UUID) (*domain.TwoFactor, error) {
+	query := `SELECT totp_secret, totp_enabled_at, totp_last_used_step
+		FROM users WHERE id = $1 AND deleted_at IS NULL
+	`
+	twoFactor := domain.TwoFactor{}
+
+	err := db.QueryRow(query, userId).Scan(&twoFactor.Secret, &twoFactor.EnabledAt, &twoFactor.LastUsedStep)
+	if err != nil {
+		return nil, err
+	}
+
+	return &twoFactor, nil
+}
+
+// SetPendingSecret stores a secret that only takes effect once confirmed. It
+// does nothing when two-factor authentication is already enabled.
+func (t *twoFactorRepository) SetPendingSecret(db *sql.DB, userId uuid.UUID, secret string) (bool, error) {
+	query := `
+		UPDATE users
+		SET totp_secret = $2
+		WHERE id = $1
+			AND totp_enabled_at IS NULL
+	`
+
+	res, err := db.Exec(query, userId, secret)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// UseTimeStep records step as the last accepted code. It fails when the same
+// or a later step was already used, so every code works only once.
+func (t *twoFactorRepository) UseTimeStep(db *sql.DB, userId uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE users
+		SET totp_last_used_step = $2
+		WHERE id = $1
+			AND totp_last_used_step < $2
+	`
+
+	res, err := db.Exec(query, userId, step)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (t *twoFactorRepository) EnableTwoFactor(ctx context.Context, tx *sql.Tx, userId uuid.UUID, step int64) error {
+	query := `
+		UPDATE users
+		SET totp_enabled_at = now(),
+			totp_last_used_step = $2
+		WHERE id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId, step)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *twoFactorRepository) DisableTwoFactor(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET totp_secret = NULL,
+			totp_enabled_at = NULL,
+			totp_last_used_step = 0
+		WHERE id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userId uuid.UUID, codeHashes []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO user_recovery_codes (id, user_id, code_hash)
+		VALUES ($1, $2, $3)`
+
+	for _, codeHash := range codeHashes {
+		_, err = tx.ExecContext(ctx, query, uuid.New(), userId, codeHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *twoFactorRepository) UseRecoveryCode(db *sql.DB, userId uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1
+			AND code_hash = $2
+			AND used_at IS NULL
+	`
+
+	res, err := db.Exec(query, userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
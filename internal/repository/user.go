@@ -2,15 +2,26 @@ package repository
 
 import (
 	"cats-social/internal/domain"
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// ErrEmailTaken is returned when another account already holds the email.
+// Deleted accounts give their email up.
+var ErrEmailTaken = errors.New("email is already used by another account")
+
 type UserRepository interface {
 	CreateNewUser(db *sql.DB, userPayload *domain.User) error
 	GetById(db *sql.DB, userId uuid.UUID) (*domain.User, error)
 	GetByEmail(db *sql.DB, userEmail string) (*domain.User, error)
+	UpdatePassword(ctx context.Context, tx *sql.Tx, userId uuid.UUID, password string) error
+	UpdateProfile(ctx context.Context, tx *sql.Tx, user *domain.User) error
+	SoftDeleteUser(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error
+	CreateVerifiedUser(ctx context.Context, tx *sql.Tx, userPayload *domain.User) error
 }
 
 type userRepository struct{}
@@ -24,6 +35,9 @@ func (u *userRepository) CreateNewUser(db *sql.DB, userPayload *domain.User) err
 		VALUES ($1, $2, $3, $4)`
 
 	_, err := db.Exec(query, userPayload.Id, userPayload.Name, userPayload.Email, userPayload.Password)
+	if isEmailTaken(err) {
+		return ErrEmailTaken
+	}
 	if err != nil {
 		return err
 	}
@@ -31,12 +45,12 @@ func (u *userRepository) CreateNewUser(db *sql.DB, userPayload *domain.User) err
 }
 
 func (u *userRepository) GetById(db *sql.DB, userId uuid.UUID) (*domain.User, error) {
-	query := `SELECT id, email, name, password
-		FROM users WHERE id = $1
+	query := `SELECT id, email, name, password, email_verified_at, created_at, role, disabled_at, totp_enabled_at
+		FROM users WHERE id = $1 AND deleted_at IS NULL
 	`
 	user := domain.User{}
 
-	err := db.QueryRow(query, userId).Scan(&user.Id, &user.Email, &user.Name, &user.Password)
+	err := db.QueryRow(query, userId).Scan(&user.Id, &user.Email, &user.Name, &user.Password, &user.EmailVerifiedAt, &user.CreatedAt, &user.Role, &user.DisabledAt, &user.TOTPEnabledAt)
 	if err != nil {
 		return nil, err
 	}
@@ -45,15 +59,85 @@ func (u *userRepository) GetById(db *sql.DB, userId uuid.UUID) (*domain.User, er
 }
 
 func (u *userRepository) GetByEmail(db *sql.DB, userEmail string) (*domain.User, error) {
-	query := `SELECT id, email, name, password
-		FROM users WHERE email = $1
+	query := `SELECT id, email, name, password, email_verified_at, created_at, role, disabled_at, totp_enabled_at
+		FROM users WHERE email = $1 AND deleted_at IS NULL
 	`
 	user := domain.User{}
 
-	err := db.QueryRow(query, userEmail).Scan(&user.Id, &user.Email, &user.Name, &user.Password)
+	err := db.QueryRow(query, userEmail).Scan(&user.Id, &user.Email, &user.Name, &user.Password, &user.EmailVerifiedAt, &user.CreatedAt, &user.Role, &user.DisabledAt, &user.TOTPEnabledAt)
 	if err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
+
+func (u *userRepository) UpdatePassword(ctx context.Context, tx *sql.Tx, userId uuid.UUID, password string) error {
+	query := `UPDATE users SET password = $2 WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, userId, password)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *userRepository) UpdateProfile(ctx context.Context, tx *sql.Tx, user *domain.User) error {
+	query := `
+		UPDATE users
+		SET name = $2,
+			email = $3,
+			email_verified_at = $4
+		WHERE id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, user.Id, user.Name, user.Email, user.EmailVerifiedAt)
+	if isEmailTaken(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *userRepository) SoftDeleteUser(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET deleted_at = now()
+		WHERE id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateVerifiedUser creates a user whose email was already verified
+// elsewhere, e.g. by an identity provider.
+func (u *userRepository) CreateVerifiedUser(ctx context.Context, tx *sql.Tx, userPayload *domain.User) error {
+	query := `INSERT INTO users (id, name, email, password, email_verified_at)
+		VALUES ($1, $2, $3, $4, now())`
+
+	_, err := tx.ExecContext(ctx, query, userPayload.Id, userPayload.Name, userPayload.Email, userPayload.Password)
+	if isEmailTaken(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// isEmailTaken recognises a violation of the unique index on the email of
+// accounts that are not deleted.
+func isEmailTaken(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "unique_email"
+}
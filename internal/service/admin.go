@@ -0,0 +1,188 @@
+package service
+
+import (
+	"cats-social/internal/blobstore"
+	"cats-social/internal/domain"
+	"cats-social/internal/imaging"
+	"cats-social/internal/repository"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type AdminService interface {
+	GetAllUsers(ctx context.Context, limit int, offset int) ([]domain.AdminUserResponse, domain.MessageErr)
+	DisableUser(ctx context.Context, admin *domain.User, userId uuid.UUID, reason string) domain.MessageErr
+	EnableUser(ctx context.Context, admin *domain.User, userId uuid.UUID, reason string) domain.MessageErr
+	HardDeleteCat(ctx context.Context, admin *domain.User, catId uuid.UUID, reason string) domain.MessageErr
+	CancelCatMatch(ctx context.Context, admin *domain.User, matchId uuid.UUID, reason string) domain.MessageErr
+}
+
+type adminService struct {
+	db                 *sql.DB
+	adminRepository    repository.AdminRepository
+	sessionRepository  repository.SessionRepository
+	catImageRepository repository.CatImageRepository
+	blobStore          blobstore.BlobStore
+}
+
+func NewAdminService(db *sql.DB, adminRepository repository.AdminRepository, sessionRepository repository.SessionRepository, catImageRepository repository.CatImageRepository, blobStore blobstore.BlobStore) AdminService {
+	return &adminService{
+		db:                 db,
+		adminRepository:    adminRepository,
+		sessionRepository:  sessionRepository,
+		catImageRepository: catImageRepository,
+		blobStore:          blobStore,
+	}
+}
+
+func (a *adminService) GetAllUsers(ctx context.Context, limit int, offset int) ([]domain.AdminUserResponse, domain.MessageErr) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	users, err := a.adminRepository.GetAllUsers(ctx, tx, limit, offset)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	tx.Commit()
+
+	return users, nil
+}
+
+func (a *adminService) DisableUser(ctx context.Context, admin *domain.User, userId uuid.UUID, reason string) domain.MessageErr {
+	if admin.Id == userId {
+		return domain.NewBadRequest("cannot disable your own account")
+	}
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	found, err := a.adminRepository.SetUserDisabled(ctx, tx, userId, true)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+	if !found {
+		return domain.NewNotFoundError("user is not found")
+	}
+
+	err = a.sessionRepository.RevokeUserSessions(ctx, tx, userId)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	auditLog := domain.NewAdminAuditLog(admin.Id, domain.AdminActionDisableUser, domain.AuditTargetUser, userId, reason)
+	err = a.adminRepository.CreateAuditLog(ctx, tx, auditLog)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.NewInternalServerError("Failed to commit transaction")
+	}
+
+	return nil
+}
+
+func (a *adminService) EnableUser(ctx context.Context, admin *domain.User, userId uuid.UUID, reason string) domain.MessageErr {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	found, err := a.adminRepository.SetUserDisabled(ctx, tx, userId, false)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+	if !found {
+		return domain.NewNotFoundError("user is not found")
+	}
+
+	auditLog := domain.NewAdminAuditLog(admin.Id, domain.AdminActionEnableUser, domain.AuditTargetUser, userId, reason)
+	err = a.adminRepository.CreateAuditLog(ctx, tx, auditLog)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.NewInternalServerError("Failed to commit transaction")
+	}
+
+	return nil
+}
+
+// HardDeleteCat removes the cat for good, image files included.
+func (a *adminService) HardDeleteCat(ctx context.Context, admin *domain.User, catId uuid.UUID, reason string) domain.MessageErr {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	// the image rows go with the cat, their keys are needed afterwards
+	images, err := a.catImageRepository.GetCatImages(ctx, tx, catId)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	found, err := a.adminRepository.HardDeleteCat(ctx, tx, catId)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+	if !found {
+		return domain.NewNotFoundError("cat is not found")
+	}
+
+	auditLog := domain.NewAdminAuditLog(admin.Id, domain.AdminActionHardDeleteCat, domain.AuditTargetCat, catId, reason)
+	err = a.adminRepository.CreateAuditLog(ctx, tx, auditLog)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.NewInternalServerError("Failed to commit transaction")
+	}
+
+	imaging.DeleteImageBlobs(a.blobStore, images)
+
+	return nil
+}
+
+func (a *adminService) CancelCatMatch(ctx context.Context, admin *domain.User, matchId uuid.UUID, reason string) domain.MessageErr {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	found, err := a.adminRepository.CancelCatMatch(ctx, tx, matchId)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+	if !found {
+		return domain.NewNotFoundError("Cat match request is not found")
+	}
+
+	auditLog := domain.NewAdminAuditLog(admin.Id, domain.AdminActionCancelCatMatch, domain.AuditTargetCatMatch, matchId, reason)
+	err = a.adminRepository.CreateAuditLog(ctx, tx, auditLog)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.NewInternalServerError("Failed to commit transaction")
+	}
+
+	return nil
+}
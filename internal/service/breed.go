@@ -0,0 +1,341 @@
+package service
+
+import (
+	"cats-social/internal/domain"
+	"cats-social/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// breedCatalogTTL bounds how long another instance keeps serving a catalogue
+// that was changed elsewhere.
+const breedCatalogTTL = time.Minute * 5
+
+// BreedCatalog keeps the breed catalogue in memory for validating races.
+// Changes made through BreedService invalidate it at once; instances that
+// did not make the change reload it after breedCatalogTTL.
+type BreedCatalog struct {
+	db              *sql.DB
+	breedRepository repository.BreedRepository
+
+	mu       sync.Mutex
+	breeds   []domain.Breed
+	names    map[string]string
+	loadedAt time.Time
+}
+
+func NewBreedCatalog(db *sql.DB, breedRepository repository.BreedRepository) *BreedCatalog {
+	return &BreedCatalog{
+		db:              db,
+		breedRepository: breedRepository,
+	}
+}
+
+// Breeds returns the catalogue by name. The slice is shared, do not modify
+// it.
+func (c *BreedCatalog) Breeds() ([]domain.Breed, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	return c.breeds, nil
+}
+
+// Resolve returns the name of the breed race names, by its name or one of
+// its aliases in any case, and false when there is no such breed.
+func (c *BreedCatalog) Resolve(race string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return "", false, err
+	}
+
+	name, ok := c.names[breedKey(race)]
+	return name, ok, nil
+}
+
+// Invalidate makes the next lookup read the catalogue again.
+func (c *BreedCatalog) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.breeds = nil
+}
+
+// load reads the catalogue when there is none or it is too old. c.mu must be
+// held.
+func (c *BreedCatalog) load() error {
+	if c.breeds != nil && time.Since(c.loadedAt) < breedCatalogTTL {
+		return nil
+	}
+
+	breeds, err := c.breedRepository.GetBreeds(c.db)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]string{}
+	for _, breed := range breeds {
+		for _, alias := range breed.Aliases {
+			names[breedKey(alias)] = breed.Name
+		}
+	}
+	// a name wins over an alias that happens to equal it
+	for _, breed := range breeds {
+		names[breedKey(breed.Name)] = breed.Name
+	}
+
+	c.breeds = breeds
+	c.names = names
+	c.loadedAt = time.Now()
+
+	return nil
+}
+
+func breedKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// resolveRace gives the breed name to store for race, telling which races
+// there are when it is none of them.
+func resolveRace(catalog *BreedCatalog, race string) (string, domain.MessageErr) {
+	name, ok, err := catalog.Resolve(race)
+	if err != nil {
+		return "", domain.NewInternalServerError("something went wrong")
+	}
+	if ok {
+		return name, nil
+	}
+
+	breeds, err := catalog.Breeds()
+	if err != nil {
+		return "", domain.NewInternalServerError("something went wrong")
+	}
+	names := make([]string, 0, len(breeds))
+	for _, breed := range breeds {
+		names = append(names, breed.Name)
+	}
+
+	return "", domain.NewBadRequest(fmt.Sprintf("accepted race is only %s", strings.Join(names, ", ")))
+}
+
+// resolveRaces gives the breed names of races given to filter by, without
+// duplicates.
+func resolveRaces(catalog *BreedCatalog, races []string) ([]string, domain.MessageErr) {
+	res := []string{}
+	seen := map[string]bool{}
+	for _, race := range races {
+		name, ok, err := catalog.Resolve(race)
+		if err != nil {
+			return nil, domain.NewInternalServerError("something went wrong")
+		}
+		if !ok {
+			return nil, domain.NewBadRequest(fmt.Sprintf("unknown race %s", race))
+		}
+		if !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+
+	return res, nil
+}
+
+type BreedService interface {
+	GetBreeds() ([]domain.Breed, domain.MessageErr)
+	CreateBreed(ctx context.Context, admin *domain.User, body domain.BreedRequest) (*domain.Breed, domain.MessageErr)
+	UpdateBreed(ctx context.Context, admin *domain.User, breedId uuid.UUID, body domain.BreedRequest) (*domain.Breed, domain.MessageErr)
+	DeleteBreed(ctx context.Context, admin *domain.User, breedId uuid.UUID, reason string) domain.MessageErr
+}
+
+type breedService struct {
+	db              *sql.DB
+	breedRepository repository.BreedRepository
+	adminRepository repository.AdminRepository
+	catalog         *BreedCatalog
+}
+
+func NewBreedService(db *sql.DB, breedRepository repository.BreedRepository, adminRepository repository.AdminRepository, catalog *BreedCatalog) BreedService {
+	return &breedService{
+		db:              db,
+		breedRepository: breedRepository,
+		adminRepository: adminRepository,
+		catalog:         catalog,
+	}
+}
+
+func (b *breedService) GetBreeds() ([]domain.Breed, domain.MessageErr) {
+	breeds, err := b.catalog.Breeds()
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	return breeds, nil
+}
+
+func (b *breedService) CreateBreed(ctx context.Context, admin *domain.User, body domain.BreedRequest) (*domain.Breed, domain.MessageErr) {
+	breed := domain.NewBreedFromRequest(body)
+	if msgErr := b.checkNames(breed); msgErr != nil {
+		return nil, msgErr
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	err = b.breedRepository.CreateBreed(ctx, tx, breed)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	msgErr := b.audit(ctx, tx, admin, domain.AdminActionCreateBreed, breed, body.Reason)
+	if msgErr != nil {
+		return nil, msgErr
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to commit transaction")
+	}
+	b.catalog.Invalidate()
+
+	return breed, nil
+}
+
+// UpdateBreed replaces the breed. A new name is carried over to its cats.
+func (b *breedService) UpdateBreed(ctx context.Context, admin *domain.User, breedId uuid.UUID, body domain.BreedRequest) (*domain.Breed, domain.MessageErr) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	current, err := b.breedRepository.GetBreed(ctx, tx, breedId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewNotFoundError("breed is not found")
+		}
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	breed := domain.NewBreedFromRequest(body)
+	breed.ID = current.ID
+	breed.CreatedAt = current.CreatedAt
+	if msgErr := b.checkNames(breed); msgErr != nil {
+		return nil, msgErr
+	}
+
+	err = b.breedRepository.UpdateBreed(ctx, tx, breed)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	msgErr := b.audit(ctx, tx, admin, domain.AdminActionUpdateBreed, breed, body.Reason)
+	if msgErr != nil {
+		return nil, msgErr
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to commit transaction")
+	}
+	b.catalog.Invalidate()
+
+	return breed, nil
+}
+
+// DeleteBreed refuses breeds that cats still have, deleted cats included.
+func (b *breedService) DeleteBreed(ctx context.Context, admin *domain.User, breedId uuid.UUID, reason string) domain.MessageErr {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	breed, err := b.breedRepository.GetBreed(ctx, tx, breedId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.NewNotFoundError("breed is not found")
+		}
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	count, err := b.breedRepository.CountCatsOfBreed(ctx, tx, breed.Name)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+	if count > 0 {
+		return domain.NewBadRequest(fmt.Sprintf("breed is the race of %d cats", count))
+	}
+
+	err = b.breedRepository.DeleteBreed(ctx, tx, breedId)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	msgErr := b.audit(ctx, tx, admin, domain.AdminActionDeleteBreed, breed, reason)
+	if msgErr != nil {
+		return msgErr
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.NewInternalServerError("Failed to commit transaction")
+	}
+	b.catalog.Invalidate()
+
+	return nil
+}
+
+// checkNames makes sure the name and aliases of breed stand for no other
+// breed, ignoring case.
+func (b *breedService) checkNames(breed *domain.Breed) domain.MessageErr {
+	breeds, err := b.breedRepository.GetBreeds(b.db)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	taken := map[string]string{}
+	for _, other := range breeds {
+		if other.ID == breed.ID {
+			continue
+		}
+		taken[breedKey(other.Name)] = other.Name
+		for _, alias := range other.Aliases {
+			taken[breedKey(alias)] = other.Name
+		}
+	}
+
+	for _, name := range append([]string{breed.Name}, breed.Aliases...) {
+		if other, ok := taken[breedKey(name)]; ok {
+			return domain.NewBadRequest(fmt.Sprintf("%s already names the breed %s", name, other))
+		}
+	}
+
+	return nil
+}
+
+func (b *breedService) audit(ctx context.Context, tx *sql.Tx, admin *domain.User, action string, breed *domain.Breed, reason string) domain.MessageErr {
+	auditLog := domain.NewAdminAuditLog(admin.Id, action, domain.AuditTargetBreed, breed.ID, reason)
+	auditLog.Metadata["name"] = breed.Name
+
+	err := b.adminRepository.CreateAuditLog(ctx, tx, auditLog)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	return nil
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"bytes"
+	"cats-social/internal/blobstore"
+	"cats-social/internal/domain"
+	"cats-social/internal/imaging"
+	"cats-social/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type CatImageService interface {
+	GetCatImages(ctx context.Context, user *domain.User, catId uuid.UUID) ([]domain.CatImageResponse, domain.MessageErr)
+	UploadCatImages(ctx context.Context, user *domain.User, catId uuid.UUID, files [][]byte) ([]domain.CatImageResponse, domain.MessageErr)
+	ReorderCatImages(ctx context.Context, user *domain.User, catId uuid.UUID, imageIds []uuid.UUID) ([]domain.CatImageResponse, domain.MessageErr)
+	DeleteCatImage(ctx context.Context, user *domain.User, catId uuid.UUID, imageId uuid.UUID) domain.MessageErr
+}
+
+type catImageService struct {
+	db                 *sql.DB
+	catRepository      repository.CatRepository
+	catImageRepository repository.CatImageRepository
+	blobStore          blobstore.BlobStore
+	variantWorker      *imaging.Worker
+}
+
+func NewCatImageService(db *sql.DB, catRepository repository.CatRepository, catImageRepository repository.CatImageRepository, blobStore blobstore.BlobStore, variantWorker *imaging.Worker) CatImageService {
+	return &catImageService{
+		db:                 db,
+		catRepository:      catRepository,
+		catImageRepository: catImageRepository,
+		blobStore:          blobStore,
+		variantWorker:      variantWorker,
+	}
+}
+
+func (c *catImageService) GetCatImages(ctx context.Context, user *domain.User, catId uuid.UUID) ([]domain.CatImageResponse, domain.MessageErr) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	owner, err := c.catRepository.CheckOwnerCat(ctx, tx, catId, user.Id)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	if !owner {
+		return nil, domain.NewNotFoundError("cat is not found")
+	}
+
+	images, err := c.catImageRepository.GetCatImages(ctx, tx, catId)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	tx.Commit()
+
+	return newCatImageResponses(images), nil
+}
+
+// UploadCatImages stores the files first and only then records them, so a
+// failed upload never leaves a url to a missing file behind.
+func (c *catImageService) UploadCatImages(ctx context.Context, user *domain.User, catId uuid.UUID, files [][]byte) ([]domain.CatImageResponse, domain.MessageErr) {
+	if len(files) < 1 {
+		return nil, domain.NewBadRequest("images at least have 1 image")
+	}
+
+	images := make([]*domain.CatImage, 0, len(files))
+	for i, file := range files {
+		image, msgErr := inspectCatImage(catId, file)
+		if msgErr != nil {
+			return nil, domain.NewBadRequest(fmt.Sprintf("image %d: %s", i+1, msgErr.Message()))
+		}
+		images = append(images, image)
+
+		// the original is public, it must not give away where the cat lives
+		files[i] = imaging.StripGPS(file)
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	owner, err := c.catRepository.CheckOwnerCat(ctx, tx, catId, user.Id)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	if !owner {
+		return nil, domain.NewNotFoundError("cat is not found")
+	}
+
+	err = c.catImageRepository.LockCat(ctx, tx, catId)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	existing, err := c.catImageRepository.GetCatImages(ctx, tx, catId)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	if len(existing)+len(images) > domain.CatImageMaxPerCat {
+		return nil, domain.NewBadRequest(fmt.Sprintf("a cat can have at most %d images", domain.CatImageMaxPerCat))
+	}
+
+	nextPosition := 0
+	for _, image := range existing {
+		nextPosition = max(nextPosition, image.Position+1)
+	}
+
+	stored := []string{}
+	for i, image := range images {
+		err = c.blobStore.Put(ctx, image.StorageKey, files[i], image.ContentType)
+		if err != nil {
+			c.deleteBlobs(stored)
+			log.Printf("failed to store cat image: %s", err)
+			return nil, domain.NewInternalServerError("something went wrong")
+		}
+		stored = append(stored, image.StorageKey)
+
+		image.URL = c.blobStore.URL(image.StorageKey)
+		image.Position = nextPosition + i
+
+		err = c.catImageRepository.CreateCatImage(ctx, tx, image)
+		if err != nil {
+			c.deleteBlobs(stored)
+			return nil, domain.NewInternalServerError("something went wrong")
+		}
+	}
+
+	err = c.catImageRepository.SyncCatImageUrls(ctx, tx, catId)
+	if err != nil {
+		c.deleteBlobs(stored)
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		c.deleteBlobs(stored)
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	c.variantWorker.Notify()
+
+	res := make([]domain.CatImageResponse, 0, len(images))
+	for _, image := range images {
+		res = append(res, domain.NewCatImageResponse(image))
+	}
+
+	return res, nil
+}
+
+// ReorderCatImages expects every image of the cat exactly once, in the new
+// order.
+func (c *catImageService) ReorderCatImages(ctx context.Context, user *domain.User, catId uuid.UUID, imageIds []uuid.UUID) ([]domain.CatImageResponse, domain.MessageErr) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	owner, err := c.catRepository.CheckOwnerCat(ctx, tx, catId, user.Id)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	if !owner {
+		return nil, domain.NewNotFoundError("cat is not found")
+	}
+
+	err = c.catImageRepository.LockCat(ctx, tx, catId)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	images, err := c.catImageRepository.GetCatImages(ctx, tx, catId)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	byId := map[uuid.UUID]domain.CatImage{}
+	for _, image := range images {
+		byId[image.ID] = image
+	}
+
+	if len(imageIds) != len(images) {
+		return nil, domain.NewBadRequest("imageIds should list every image of the cat once")
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range imageIds {
+		if _, ok := byId[id]; !ok || seen[id] {
+			return nil, domain.NewBadRequest("imageIds should list every image of the cat once")
+		}
+		seen[id] = true
+	}
+
+	reordered := make([]domain.CatImage, 0, len(imageIds))
+	for position, id := range imageIds {
+		err = c.catImageRepository.UpdateCatImagePosition(ctx, tx, id, position)
+		if err != nil {
+			return nil, domain.NewInternalServerError("something went wrong")
+		}
+
+		image := byId[id]
+		image.Position = position
+		reordered = append(reordered, image)
+	}
+
+	err = c.catImageRepository.SyncCatImageUrls(ctx, tx, catId)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	return newCatImageResponses(reordered), nil
+}
+
+func (c *catImageService) DeleteCatImage(ctx context.Context, user *domain.User, catId uuid.UUID, imageId uuid.UUID) domain.MessageErr {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	owner, err := c.catRepository.CheckOwnerCat(ctx, tx, catId, user.Id)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+	if !owner {
+		return domain.NewNotFoundError("cat is not found")
+	}
+
+	err = c.catImageRepository.LockCat(ctx, tx, catId)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	image, err := c.catImageRepository.DeleteCatImage(ctx, tx, catId, imageId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.NewNotFoundError("image is not found")
+		}
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = c.catImageRepository.SyncCatImageUrls(ctx, tx, catId)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	// the url is gone from the cat already, a leftover file is harmless
+	c.deleteBlobs(append([]string{image.StorageKey}, imaging.VariantKeys(image.StorageKey)...))
+
+	return nil
+}
+
+func (c *catImageService) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		if err := c.blobStore.Delete(context.Background(), key); err != nil {
+			log.Printf("failed to delete blob %s: %s", key, err)
+		}
+	}
+}
+
+// inspectCatImage checks the real type and the dimensions of an uploaded
+// file; the file name and the declared content type are not trusted.
+func inspectCatImage(catId uuid.UUID, file []byte) (*domain.CatImage, domain.MessageErr) {
+	if len(file) > domain.CatImageMaxBytes {
+		return nil, domain.NewBadRequest(fmt.Sprintf("image should be at most %d MB", domain.CatImageMaxBytes>>20))
+	}
+
+	contentType := http.DetectContentType(file)
+	if _, ok := domain.CatImageContentTypes[contentType]; !ok {
+		return nil, domain.NewBadRequest("image should be a jpeg, png or gif")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(file))
+	if err != nil {
+		return nil, domain.NewBadRequest("image cannot be decoded")
+	}
+	if config.Width < domain.CatImageMinDimension || config.Height < domain.CatImageMinDimension {
+		return nil, domain.NewBadRequest(fmt.Sprintf("image should be at least %dx%d pixels", domain.CatImageMinDimension, domain.CatImageMinDimension))
+	}
+	if config.Width > domain.CatImageMaxDimension || config.Height > domain.CatImageMaxDimension {
+		return nil, domain.NewBadRequest(fmt.Sprintf("image should be at most %dx%d pixels", domain.CatImageMaxDimension, domain.CatImageMaxDimension))
+	}
+
+	catImage := domain.NewCatImage(catId, contentType)
+	catImage.Width = config.Width
+	catImage.Height = config.Height
+	catImage.SizeBytes = len(file)
+
+	return catImage, nil
+}
+
+func newCatImageResponses(images []domain.CatImage) []domain.CatImageResponse {
+	res := make([]domain.CatImageResponse, 0, len(images))
+	for _, image := range images {
+		res = append(res, domain.NewCatImageResponse(&image))
+	}
+
+	return res
+}
+
+// getCatImageSummaries loads the images of the cats for listing them, keyed
+// by cat. Every cat gets an entry, empty when it has no images.
+func getCatImageSummaries(db *sql.DB, catImageRepository repository.CatImageRepository, catIds []uuid.UUID) (map[uuid.UUID][]domain.CatImageSummary, error) {
+	summaries := make(map[uuid.UUID][]domain.CatImageSummary, len(catIds))
+	for _, id := range catIds {
+		summaries[id] = []domain.CatImageSummary{}
+	}
+	if len(catIds) == 0 {
+		return summaries, nil
+	}
+
+	images, err := catImageRepository.GetCatImagesByCatIds(db, catIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		summaries[image.CatID] = append(summaries[image.CatID], domain.NewCatImageSummary(&image))
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"cats-social/internal/domain"
+	"cats-social/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type CatLikeService interface {
+	LikeCat(ctx context.Context, user *domain.User, catId uuid.UUID) (*domain.CatLikeStatusResponse, domain.MessageErr)
+	UnlikeCat(ctx context.Context, user *domain.User, catId uuid.UUID) (*domain.CatLikeStatusResponse, domain.MessageErr)
+	GetLikedCats(ctx context.Context, user *domain.User, page domain.PageRequest) ([]domain.LikedCat, *domain.Pagination, domain.MessageErr)
+	GetCatLikers(ctx context.Context, user *domain.User, catId uuid.UUID, page domain.PageRequest) ([]domain.CatLiker, *domain.Pagination, domain.MessageErr)
+}
+
+type catLikeService struct {
+	db                 *sql.DB
+	catRepository      repository.CatRepository
+	catLikeRepository  repository.CatLikeRepository
+	catImageRepository repository.CatImageRepository
+}
+
+func NewCatLikeService(db *sql.DB, catRepository repository.CatRepository, catLikeRepository repository.CatLikeRepository, catImageRepository repository.CatImageRepository) CatLikeService {
+	return &catLikeService{
+		db:                 db,
+		catRepository:      catRepository,
+		catLikeRepository:  catLikeRepository,
+		catImageRepository: catImageRepository,
+	}
+}
+
+// LikeCat bookmarks someone else's cat. Liking it again changes nothing.
+func (c *catLikeService) LikeCat(ctx context.Context, user *domain.User, catId uuid.UUID) (*domain.CatLikeStatusResponse, domain.MessageErr) {
+	return c.setLike(ctx, user, catId, true)
+}
+
+// UnlikeCat removes the bookmark, if there is one.
+func (c *catLikeService) UnlikeCat(ctx context.Context, user *domain.User, catId uuid.UUID) (*domain.CatLikeStatusResponse, domain.MessageErr) {
+	return c.setLike(ctx, user, catId, false)
+}
+
+func (c *catLikeService) setLike(ctx context.Context, user *domain.User, catId uuid.UUID, liked bool) (*domain.CatLikeStatusResponse, domain.MessageErr) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	ownerId, err := c.catLikeRepository.GetCatOwnerId(ctx, tx, catId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewNotFoundError("cat is not found")
+		}
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	if liked {
+		if ownerId == user.Id {
+			return nil, domain.NewBadRequest("cannot like your own cat")
+		}
+		err = c.catLikeRepository.LikeCat(ctx, tx, user.Id, catId)
+	} else {
+		err = c.catLikeRepository.UnlikeCat(ctx, tx, user.Id, catId)
+	}
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	count, err := c.catLikeRepository.CountCatLikes(ctx, tx, catId)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	return &domain.CatLikeStatusResponse{Liked: liked, LikeCount: count}, nil
+}
+
+func (c *catLikeService) GetLikedCats(ctx context.Context, user *domain.User, page domain.PageRequest) ([]domain.LikedCat, *domain.Pagination, domain.MessageErr) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	cats, err := c.catLikeRepository.GetLikedCats(ctx, tx, user.Id, page)
+	if err != nil {
+		return nil, nil, domain.NewInternalServerError("something went wrong")
+	}
+	cats, hasMore := trimPage(cats, page)
+
+	var first, last *domain.Cursor
+	if len(cats) > 0 {
+		first = domain.NewLikedCatCursor(&cats[0])
+		last = domain.NewLikedCatCursor(&cats[len(cats)-1])
+	}
+	pagination := domain.NewPagination(page, first, last, hasMore, page.Cursor != nil)
+
+	if page.IncludeTotal {
+		total, err := c.catLikeRepository.CountLikedCats(ctx, tx, user.Id)
+		if err != nil {
+			return nil, nil, domain.NewInternalServerError("something went wrong")
+		}
+		pagination.Total = &total
+	}
+	tx.Commit()
+
+	catIds := make([]uuid.UUID, 0, len(cats))
+	for _, cat := range cats {
+		catIds = append(catIds, cat.ID)
+	}
+
+	images, err := getCatImageSummaries(c.db, c.catImageRepository, catIds)
+	if err != nil {
+		return nil, nil, domain.NewInternalServerError("something went wrong")
+	}
+	for i := range cats {
+		cats[i].Images = images[cats[i].ID]
+	}
+
+	return cats, &pagination, nil
+}
+
+// GetCatLikers is only for the owner of the cat, to anyone else the cat is
+// not found.
+func (c *catLikeService) GetCatLikers(ctx context.Context, user *domain.User, catId uuid.UUID, page domain.PageRequest) ([]domain.CatLiker, *domain.Pagination, domain.MessageErr) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	owner, err := c.catRepository.CheckOwnerCat(ctx, tx, catId, user.Id)
+	if err != nil {
+		return nil, nil, domain.NewInternalServerError("something went wrong")
+	}
+	if !owner {
+		return nil, nil, domain.NewNotFoundError("cat is not found")
+	}
+
+	likers, err := c.catLikeRepository.GetCatLikers(ctx, tx, catId, page)
+	if err != nil {
+		return nil, nil, domain.NewInternalServerError("something went wrong")
+	}
+	likers, hasMore := trimPage(likers, page)
+
+	var first, last *domain.Cursor
+	if len(likers) > 0 {
+		first = domain.NewCatLikerCursor(&likers[0])
+		last = domain.NewCatLikerCursor(&likers[len(likers)-1])
+	}
+	pagination := domain.NewPagination(page, first, last, hasMore, page.Cursor != nil)
+
+	if page.IncludeTotal {
+		total, err := c.catLikeRepository.CountCatLikes(ctx, tx, catId)
+		if err != nil {
+			return nil, nil, domain.NewInternalServerError("something went wrong")
+		}
+		pagination.Total = &total
+	}
+	tx.Commit()
+
+	return likers, &pagination, nil
+}
@@ -2,117 +2,174 @@ package service
 
 import (
 	"cats-social/internal/domain"
+	"cats-social/internal/matching"
 	"cats-social/internal/repository"
 	"context"
 	"database/sql"
+	"errors"
+	"math"
+	"sort"
 
 	"github.com/google/uuid"
 )
 
 type CatMatchService interface {
-	CreateCatMatch(ctx context.Context, user *domain.User, catMatchPayload *domain.CatMatch) domain.MessageErr
-	GetCatMatchesByIssuerOrReceiverID(ctx context.Context, userId string) ([]domain.CatMatchResponse, domain.MessageErr)
+	CreateCatMatch(ctx context.Context, user *domain.User, catMatchPayload *domain.CatMatch) ([]string, domain.MessageErr)
+	GetCatMatchesByIssuerOrReceiverID(ctx context.Context, userId string, page domain.PageRequest) ([]domain.CatMatchResponse, *domain.Pagination, domain.MessageErr)
 	UpdateCatMatchByID(ctx context.Context, id string, catMatchPayload *domain.CatMatch) (string, domain.MessageErr)
 	DeleteCatMatchByID(ctx context.Context, id string, userId string) domain.MessageErr
 	ApproveCatMatch(ctx context.Context, userId string, matchId string) domain.MessageErr
 	RejectCatMatch(ctx context.Context, userId string, matchId string) domain.MessageErr
+	GetRecommendations(ctx context.Context, user *domain.User, catId uuid.UUID, prefs domain.RecommendationPreferences) ([]domain.CatRecommendation, domain.MessageErr)
 }
 
 type catMatchService struct {
-	db                 *sql.DB
-	catMatchRepository repository.CatMatchRepository
-	catRepository      repository.CatRepository
+	db                    *sql.DB
+	catMatchRepository    repository.CatMatchRepository
+	catRepository         repository.CatRepository
+	catImageRepository    repository.CatImageRepository
+	catPedigreeRepository repository.CatPedigreeRepository
+	breedCatalog          *BreedCatalog
+	scorer                matching.Scorer
+	inbreedingPolicy      matching.InbreedingPolicy
 }
 
-func NewCatMatchService(db *sql.DB, catMatchRepository repository.CatMatchRepository, catRespository repository.CatRepository) CatMatchService {
+func NewCatMatchService(db *sql.DB, catMatchRepository repository.CatMatchRepository, catRespository repository.CatRepository, catImageRepository repository.CatImageRepository, catPedigreeRepository repository.CatPedigreeRepository, breedCatalog *BreedCatalog, scorer matching.Scorer, inbreedingPolicy matching.InbreedingPolicy) CatMatchService {
 	return &catMatchService{
-		db:                 db,
-		catMatchRepository: catMatchRepository,
-		catRepository:      catRespository,
+		db:                    db,
+		catMatchRepository:    catMatchRepository,
+		catRepository:         catRespository,
+		catImageRepository:    catImageRepository,
+		catPedigreeRepository: catPedigreeRepository,
+		breedCatalog:          breedCatalog,
+		scorer:                scorer,
+		inbreedingPolicy:      inbreedingPolicy,
 	}
 }
 
-func (c *catMatchService) CreateCatMatch(ctx context.Context, user *domain.User, catMatchPayload *domain.CatMatch) domain.MessageErr {
+func (c *catMatchService) CreateCatMatch(ctx context.Context, user *domain.User, catMatchPayload *domain.CatMatch) ([]string, domain.MessageErr) {
 	tx, err := c.db.BeginTx(ctx, nil)
 	if err != nil {
-		return domain.NewBadRequest("Failed to start transaction")
+		return nil, domain.NewBadRequest("Failed to start transaction")
 	}
 	defer tx.Rollback()
 
 	bothExists, err := c.catRepository.CheckBothCatExists(ctx, tx, catMatchPayload.UserCatID, catMatchPayload.MatchCatID)
 	if err != nil {
-		return domain.NewInternalServerError("something went wrong")
+		return nil, domain.NewInternalServerError("something went wrong")
 	}
 	if !bothExists {
-		return domain.NewNotFoundError("Either user or match cat is not found")
+		return nil, domain.NewNotFoundError("Either user or match cat is not found")
 	}
 
 	owner, err := c.catRepository.CheckOwnerCat(ctx, tx, catMatchPayload.UserCatID, user.Id)
 	if err != nil {
-		return domain.NewInternalServerError("something went wrong")
+		return nil, domain.NewInternalServerError("something went wrong")
 	}
 	if !owner {
-		return domain.NewNotFoundError("You are not the cat's owner")
+		return nil, domain.NewNotFoundError("You are not the cat's owner")
 	}
 
 	hasSameSex, err := c.catRepository.CheckCatHasSameSex(ctx, tx, catMatchPayload.UserCatID, catMatchPayload.MatchCatID)
 	if err != nil {
-		return domain.NewInternalServerError("something went wrong")
+		return nil, domain.NewInternalServerError("something went wrong")
 	}
 	if hasSameSex {
-		return domain.NewBadRequest("Cat's sex is same")
+		return nil, domain.NewBadRequest("Cat's sex is same")
 	}
 
 	isMatching, err := c.catMatchRepository.CheckCatsIsMatching(ctx, tx, catMatchPayload.UserCatID, catMatchPayload.MatchCatID)
 	if err != nil {
-		return domain.NewInternalServerError("something went wrong")
+		return nil, domain.NewInternalServerError("something went wrong")
 	}
 	if isMatching {
-		return domain.NewBadRequest("User and match cat is matching")
+		return nil, domain.NewBadRequest("User and match cat is matching")
 	}
 
 	hasMatched, err := c.catRepository.CheckCatHasMatched(ctx, tx, catMatchPayload.UserCatID, catMatchPayload.MatchCatID)
 	if err != nil {
-		return domain.NewInternalServerError("something went wrong")
+		return nil, domain.NewInternalServerError("something went wrong")
 	}
 	if hasMatched {
-		return domain.NewBadRequest("Either user or match cat already has matched")
+		return nil, domain.NewBadRequest("Either user or match cat already has matched")
 	}
 
 	sameOwner, err := c.catRepository.CheckCatFromSameOwner(ctx, tx, catMatchPayload.UserCatID, catMatchPayload.MatchCatID)
 	if err != nil {
-		return domain.NewInternalServerError("something went wrong")
+		return nil, domain.NewInternalServerError("something went wrong")
 	}
 	if sameOwner {
-		return domain.NewBadRequest("User and match cat is from the same owner")
+		return nil, domain.NewBadRequest("User and match cat is from the same owner")
+	}
+
+	var warnings []string
+	if c.inbreedingPolicy.Generations > 0 {
+		ancestors, err := c.catPedigreeRepository.GetCommonAncestors(ctx, tx, catMatchPayload.UserCatID, catMatchPayload.MatchCatID, c.inbreedingPolicy.Generations)
+		if err != nil {
+			return nil, domain.NewInternalServerError("something went wrong")
+		}
+		if len(ancestors) > 0 {
+			message := matching.InbreedingMessage(ancestors)
+			if c.inbreedingPolicy.Action == matching.InbreedingBlock {
+				return nil, domain.NewBadRequest(message)
+			}
+			warnings = append(warnings, message)
+		}
 	}
 
 	_, err = c.catMatchRepository.CreateCatMatch(ctx, tx, catMatchPayload)
 	if err != nil {
-		return domain.NewBadRequest("Failed to create cat match")
+		return nil, domain.NewBadRequest("Failed to create cat match")
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return domain.NewBadRequest("Failed to commit transaction")
+		return nil, domain.NewBadRequest("Failed to commit transaction")
 	}
 
-	return nil
+	return warnings, nil
 }
 
-func (c *catMatchService) GetCatMatchesByIssuerOrReceiverID(ctx context.Context, userId string) ([]domain.CatMatchResponse, domain.MessageErr) {
+func (c *catMatchService) GetCatMatchesByIssuerOrReceiverID(ctx context.Context, userId string, page domain.PageRequest) ([]domain.CatMatchResponse, *domain.Pagination, domain.MessageErr) {
 	tx, err := c.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, domain.NewBadRequest("Failed to start transaction")
+		return nil, nil, domain.NewBadRequest("Failed to start transaction")
 	}
 	defer tx.Rollback()
 
-	catMatches, err := c.catMatchRepository.GetCatMatchesByIssuerOrReceiverID(ctx, tx, userId)
+	catMatches, err := c.catMatchRepository.GetCatMatchesByIssuerOrReceiverID(ctx, tx, userId, page)
 	if err != nil {
-		return nil, domain.NewBadRequest("Failed to get cat match")
+		return nil, nil, domain.NewBadRequest("Failed to get cat match")
+	}
+
+	catMatches, hasMore := trimPage(catMatches, page)
+
+	var first, last *domain.Cursor
+	if len(catMatches) > 0 {
+		first = domain.NewCatMatchCursor(&catMatches[0])
+		last = domain.NewCatMatchCursor(&catMatches[len(catMatches)-1])
+	}
+	pagination := domain.NewPagination(page, first, last, hasMore, page.Cursor != nil)
+
+	if page.IncludeTotal {
+		total, err := c.catMatchRepository.CountCatMatchesByIssuerOrReceiverID(ctx, tx, userId)
+		if err != nil {
+			return nil, nil, domain.NewInternalServerError("something went wrong")
+		}
+		pagination.Total = &total
 	}
 	tx.Commit()
 
+	catIds := []uuid.UUID{}
+	for _, catMatch := range catMatches {
+		catIds = append(catIds, catMatch.MatchCat.ID, catMatch.UserCat.ID)
+	}
+
+	images, err := getCatImageSummaries(c.db, c.catImageRepository, catIds)
+	if err != nil {
+		return nil, nil, domain.NewInternalServerError("something went wrong")
+	}
+
 	var catMatchResponses []domain.CatMatchResponse
 	for _, catMatch := range catMatches {
 		// TODO: create helper for mapping response
@@ -133,7 +190,11 @@ func (c *catMatchService) GetCatMatchesByIssuerOrReceiverID(ctx context.Context,
 				AgeInMonth:  catMatch.MatchCat.AgeInMonth,
 				Description: catMatch.MatchCat.Description,
 				ImageUrls:   catMatch.MatchCat.ImageUrls,
+				Images:      images[catMatch.MatchCat.ID],
+				Traits:      catMatch.MatchCat.Traits,
 				HasMatched:  catMatch.MatchCat.HasMatched,
+				LikeCount:   catMatch.MatchCat.LikeCount,
+				Liked:       catMatch.MatchCat.Liked,
 				CreatedAt:   catMatch.MatchCat.CreatedAt,
 			},
 			UserCat: domain.CatResponse{
@@ -144,13 +205,17 @@ func (c *catMatchService) GetCatMatchesByIssuerOrReceiverID(ctx context.Context,
 				AgeInMonth:  catMatch.UserCat.AgeInMonth,
 				Description: catMatch.UserCat.Description,
 				ImageUrls:   catMatch.UserCat.ImageUrls,
+				Images:      images[catMatch.UserCat.ID],
+				Traits:      catMatch.UserCat.Traits,
 				HasMatched:  catMatch.UserCat.HasMatched,
+				LikeCount:   catMatch.UserCat.LikeCount,
+				Liked:       catMatch.UserCat.Liked,
 				CreatedAt:   catMatch.UserCat.CreatedAt,
 			},
 		})
 	}
 
-	return catMatchResponses, nil
+	return catMatchResponses, &pagination, nil
 }
 
 func (c *catMatchService) UpdateCatMatchByID(ctx context.Context, id string, catMatchPayload *domain.CatMatch) (string, domain.MessageErr) {
@@ -282,3 +347,99 @@ func (c *catMatchService) RejectCatMatch(ctx context.Context, userId string, mat
 	}
 	return nil
 }
+
+// GetRecommendations ranks the cats the user's cat could be matched with.
+func (c *catMatchService) GetRecommendations(ctx context.Context, user *domain.User, catId uuid.UUID, prefs domain.RecommendationPreferences) ([]domain.CatRecommendation, domain.MessageErr) {
+	races, msgErr := resolveRaces(c.breedCatalog, prefs.Races)
+	if msgErr != nil {
+		return nil, msgErr
+	}
+	prefs.Races = races
+
+	cat, err := c.catRepository.GetCatDetail(c.db, catId, user.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewNotFoundError("cat is not found")
+		}
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	if cat.OwnedById != user.Id {
+		return nil, domain.NewNotFoundError("cat is not found")
+	}
+	if cat.HasMatched {
+		return nil, domain.NewBadRequest("cat already has matched")
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	candidates, err := c.catMatchRepository.GetMatchCandidates(ctx, tx, &cat.Cat, user.Id, domain.CatRecommendationPoolSize)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	// related cats are left out or flagged the way CreateCatMatch treats them
+	related := map[uuid.UUID][]domain.CommonAncestor{}
+	if c.inbreedingPolicy.Generations > 0 && len(candidates) > 0 {
+		candidateIds := make([]uuid.UUID, 0, len(candidates))
+		for _, candidate := range candidates {
+			candidateIds = append(candidateIds, candidate.ID)
+		}
+
+		related, err = c.catPedigreeRepository.GetCommonAncestorsWithCats(ctx, tx, cat.ID, candidateIds, c.inbreedingPolicy.Generations)
+		if err != nil {
+			return nil, domain.NewInternalServerError("something went wrong")
+		}
+	}
+	tx.Commit()
+
+	recommendations := make([]domain.CatRecommendation, 0, len(candidates))
+	for _, candidate := range candidates {
+		var warnings []string
+		if ancestors, ok := related[candidate.ID]; ok {
+			if c.inbreedingPolicy.Action == matching.InbreedingBlock {
+				continue
+			}
+			warnings = append(warnings, matching.InbreedingMessage(ancestors))
+		}
+
+		score, reasons := c.scorer.Score(&cat.Cat, &candidate, prefs)
+		if reasons == nil {
+			reasons = []string{}
+		}
+
+		// only the owner sees coordinates
+		candidate.Location = candidate.Location.Public()
+		recommendations = append(recommendations, domain.CatRecommendation{
+			Cat:      candidate,
+			Score:    math.Round(score*1000) / 10,
+			Reasons:  reasons,
+			Warnings: warnings,
+		})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].Score > recommendations[j].Score
+	})
+	if len(recommendations) > prefs.Limit {
+		recommendations = recommendations[:prefs.Limit]
+	}
+
+	catIds := make([]uuid.UUID, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		catIds = append(catIds, recommendation.ID)
+	}
+
+	images, err := getCatImageSummaries(c.db, c.catImageRepository, catIds)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	for i := range recommendations {
+		recommendations[i].Images = images[recommendations[i].ID]
+	}
+
+	return recommendations, nil
+}
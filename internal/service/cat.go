@@ -1,35 +1,86 @@
 package service
 
 import (
+	"cats-social/internal/blobstore"
 	"cats-social/internal/domain"
+	"cats-social/internal/imaging"
 	"cats-social/internal/repository"
+	"context"
 	"database/sql"
-	"net/url"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
 type CatService interface {
-	CreateCat(cat *domain.Cat) domain.MessageErr
-	GetAllCats(user *domain.User, queryParams url.Values) ([]domain.Cat, domain.MessageErr)
-	UpdateCat(user *domain.User, cat *domain.Cat) domain.MessageErr
+	CreateCat(ctx context.Context, cat *domain.Cat) domain.MessageErr
+	GetAllCats(user *domain.User, filter *domain.CatFilter) ([]domain.Cat, *domain.Pagination, domain.MessageErr)
+	GetCat(user *domain.User, catId uuid.UUID) (*domain.CatDetailResponse, domain.MessageErr)
+	GetTraitCategories() ([]domain.TraitCategory, domain.MessageErr)
+	GetPedigree(catId uuid.UUID, generations int) (*domain.PedigreeCat, domain.MessageErr)
+	UpdateCat(ctx context.Context, user *domain.User, cat *domain.Cat) domain.MessageErr
 	DeleteCat(user *domain.User, catId uuid.UUID) domain.MessageErr
 }
 
 type catService struct {
-	db            *sql.DB
-	catRepository repository.CatRepository
+	db                    *sql.DB
+	catRepository         repository.CatRepository
+	catImageRepository    repository.CatImageRepository
+	catTraitRepository    repository.CatTraitRepository
+	catPedigreeRepository repository.CatPedigreeRepository
+	breedCatalog          *BreedCatalog
+	blobStore             blobstore.BlobStore
 }
 
-func NewCatService(db *sql.DB, catRepository repository.CatRepository) CatService {
+func NewCatService(db *sql.DB, catRepository repository.CatRepository, catImageRepository repository.CatImageRepository, catTraitRepository repository.CatTraitRepository, catPedigreeRepository repository.CatPedigreeRepository, breedCatalog *BreedCatalog, blobStore blobstore.BlobStore) CatService {
 	return &catService{
-		db:            db,
-		catRepository: catRepository,
+		db:                    db,
+		catRepository:         catRepository,
+		catImageRepository:    catImageRepository,
+		catTraitRepository:    catTraitRepository,
+		catPedigreeRepository: catPedigreeRepository,
+		breedCatalog:          breedCatalog,
+		blobStore:             blobStore,
 	}
 }
 
-func (c *catService) CreateCat(cat *domain.Cat) domain.MessageErr {
-	err := c.catRepository.CreateCat(c.db, cat)
+func (c *catService) CreateCat(ctx context.Context, cat *domain.Cat) domain.MessageErr {
+	race, msgErr := resolveRace(c.breedCatalog, cat.Race)
+	if msgErr != nil {
+		return msgErr
+	}
+	cat.Race = race
+
+	if msgErr := c.checkTraits(cat.Traits); msgErr != nil {
+		return msgErr
+	}
+	if cat.Location != nil {
+		cat.Location.Round()
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if msgErr := c.checkParents(ctx, tx, cat); msgErr != nil {
+		return msgErr
+	}
+
+	err = c.catRepository.CreateCat(ctx, tx, cat)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = c.catTraitRepository.SetCatTraits(ctx, tx, cat.ID, cat.Traits)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		return domain.NewInternalServerError("something went wrong")
 	}
@@ -37,16 +88,97 @@ func (c *catService) CreateCat(cat *domain.Cat) domain.MessageErr {
 	return nil
 }
 
-func (c *catService) GetAllCats(user *domain.User, queryParams url.Values) ([]domain.Cat, domain.MessageErr) {
-	cats, err := c.catRepository.GetAllCats(c.db, user, queryParams)
+func (c *catService) GetAllCats(user *domain.User, filter *domain.CatFilter) ([]domain.Cat, *domain.Pagination, domain.MessageErr) {
+	races, msgErr := resolveRaces(c.breedCatalog, filter.Races)
+	if msgErr != nil {
+		return nil, nil, msgErr
+	}
+	filter.Races = races
+
+	if len(filter.Traits) > 0 {
+		categories, err := c.catTraitRepository.GetTraitCategories(c.db)
+		if err != nil {
+			return nil, nil, domain.NewInternalServerError("something went wrong")
+		}
+		known := traitCategoriesById(categories)
+		for _, trait := range filter.Traits {
+			if _, ok := known[trait]; !ok {
+				return nil, nil, domain.NewBadRequest(fmt.Sprintf("unknown trait %s", trait))
+			}
+		}
+	}
+
+	cats, err := c.catRepository.GetAllCats(c.db, user, filter)
+	if err != nil {
+		return nil, nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	cats, hasMore := trimPage(cats, filter.PageRequest)
+
+	var first, last *domain.Cursor
+	if len(cats) > 0 {
+		first = domain.NewCatCursor(filter, &cats[0])
+		last = domain.NewCatCursor(filter, &cats[len(cats)-1])
+	}
+	pagination := domain.NewPagination(filter.PageRequest, first, last, hasMore, filter.Cursor != nil || filter.Offset > 0)
+
+	if filter.IncludeTotal {
+		total, err := c.catRepository.CountCats(c.db, user, filter)
+		if err != nil {
+			return nil, nil, domain.NewInternalServerError("something went wrong")
+		}
+		pagination.Total = &total
+	}
+
+	catIds := make([]uuid.UUID, 0, len(cats))
+	for _, cat := range cats {
+		catIds = append(catIds, cat.ID)
+	}
+
+	images, err := getCatImageSummaries(c.db, c.catImageRepository, catIds)
+	if err != nil {
+		return nil, nil, domain.NewInternalServerError("something went wrong")
+	}
+	for i := range cats {
+		cats[i].Images = images[cats[i].ID]
+		// where other owners' cats live is only told by city and distance
+		if cats[i].OwnedById != user.Id {
+			cats[i].Location = cats[i].Location.Public()
+		}
+	}
+
+	return cats, &pagination, nil
+}
+
+func (c *catService) GetCat(user *domain.User, catId uuid.UUID) (*domain.CatDetailResponse, domain.MessageErr) {
+	detail, err := c.catRepository.GetCatDetail(c.db, catId, user.Id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewNotFoundError("cat is not found")
+		}
 		return nil, domain.NewInternalServerError("something went wrong")
 	}
 
-	return cats, nil
+	images, err := getCatImageSummaries(c.db, c.catImageRepository, []uuid.UUID{catId})
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	detail.Images = images[catId]
+
+	res := domain.NewCatDetailResponse(detail, user.Id)
+	return &res, nil
+}
+
+func (c *catService) GetTraitCategories() ([]domain.TraitCategory, domain.MessageErr) {
+	categories, err := c.catTraitRepository.GetTraitCategories(c.db)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+
+	return categories, nil
 }
 
-func (c *catService) UpdateCat(user *domain.User, cat *domain.Cat) domain.MessageErr {
+func (c *catService) UpdateCat(ctx context.Context, user *domain.User, cat *domain.Cat) domain.MessageErr {
 	catExists, err := c.catRepository.CheckCatExists(c.db, cat.ID, user.Id)
 	if err != nil {
 		return domain.NewInternalServerError("something went wrong")
@@ -63,7 +195,48 @@ func (c *catService) UpdateCat(user *domain.User, cat *domain.Cat) domain.Messag
 		return domain.NewBadRequest("cannot edit sex when already requested to match")
 	}
 
-	err = c.catRepository.UpdateCat(c.db, cat)
+	race, msgErr := resolveRace(c.breedCatalog, cat.Race)
+	if msgErr != nil {
+		return msgErr
+	}
+	cat.Race = race
+
+	if msgErr := c.checkTraits(cat.Traits); msgErr != nil {
+		return msgErr
+	}
+	if cat.Location != nil {
+		cat.Location.Round()
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NewInternalServerError("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if msgErr := c.checkParents(ctx, tx, cat); msgErr != nil {
+		return msgErr
+	}
+
+	parentAsOtherSex, err := c.catPedigreeRepository.IsParentAsOtherSex(ctx, tx, cat.ID, cat.Sex)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+	if parentAsOtherSex {
+		return domain.NewBadRequest("cannot edit sex of a cat that is a parent")
+	}
+
+	err = c.catRepository.UpdateCat(ctx, tx, cat)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = c.catTraitRepository.SetCatTraits(ctx, tx, cat.ID, cat.Traits)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		return domain.NewInternalServerError("something went wrong")
 	}
@@ -71,6 +244,8 @@ func (c *catService) UpdateCat(user *domain.User, cat *domain.Cat) domain.Messag
 	return nil
 }
 
+// DeleteCat hides the cat and removes its image files so they are no longer
+// served; a deleted cat cannot be brought back.
 func (c *catService) DeleteCat(user *domain.User, catId uuid.UUID) domain.MessageErr {
 	catExists, err := c.catRepository.CheckCatExists(c.db, catId, user.Id)
 	if err != nil {
@@ -85,5 +260,117 @@ func (c *catService) DeleteCat(user *domain.User, catId uuid.UUID) domain.Messag
 		return domain.NewInternalServerError("something went wrong")
 	}
 
+	images, err := c.catImageRepository.GetCatImagesByCatIds(c.db, []uuid.UUID{catId})
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+	imaging.DeleteImageBlobs(c.blobStore, images)
+
+	return nil
+}
+
+// checkParents makes sure the sire is a male cat and the dam a female one,
+// and that neither descends from the cat.
+func (c *catService) checkParents(ctx context.Context, tx *sql.Tx, cat *domain.Cat) domain.MessageErr {
+	parents := []struct {
+		role string
+		id   *uuid.UUID
+		sex  string
+	}{
+		{"sire", cat.SireID, "male"},
+		{"dam", cat.DamID, "female"},
+	}
+
+	for _, parent := range parents {
+		if parent.id == nil {
+			continue
+		}
+		if *parent.id == cat.ID {
+			return domain.NewBadRequest(fmt.Sprintf("a cat cannot be its own %s", parent.role))
+		}
+
+		sex, err := c.catPedigreeRepository.GetParentSex(ctx, tx, *parent.id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return domain.NewBadRequest(fmt.Sprintf("%s is not found", parent.role))
+			}
+			return domain.NewInternalServerError("something went wrong")
+		}
+		if sex != parent.sex {
+			return domain.NewBadRequest(fmt.Sprintf("%s should be %s", parent.role, parent.sex))
+		}
+
+		descends, err := c.catPedigreeRepository.IsAncestor(ctx, tx, cat.ID, *parent.id)
+		if err != nil {
+			return domain.NewInternalServerError("something went wrong")
+		}
+		if descends {
+			return domain.NewBadRequest(fmt.Sprintf("%s descends from the cat", parent.role))
+		}
+	}
+
+	return nil
+}
+
+// GetPedigree returns the cat with its ancestors, generations deep.
+func (c *catService) GetPedigree(catId uuid.UUID, generations int) (*domain.PedigreeCat, domain.MessageErr) {
+	ancestors, err := c.catPedigreeRepository.GetAncestors(c.db, catId, generations)
+	if err != nil {
+		return nil, domain.NewInternalServerError("something went wrong")
+	}
+	if len(ancestors) == 0 {
+		return nil, domain.NewNotFoundError("cat is not found")
+	}
+
+	return domain.NewPedigree(catId, ancestors, generations), nil
+}
+
+// checkTraits validates the traits of a cat against the vocabulary.
+func (c *catService) checkTraits(traits []string) domain.MessageErr {
+	if len(traits) == 0 {
+		return nil
+	}
+	if len(traits) > domain.CatMaxTraits {
+		return domain.NewBadRequest(fmt.Sprintf("a cat can have at most %d traits", domain.CatMaxTraits))
+	}
+
+	categories, err := c.catTraitRepository.GetTraitCategories(c.db)
+	if err != nil {
+		return domain.NewInternalServerError("something went wrong")
+	}
+	known := traitCategoriesById(categories)
+
+	seen := map[string]bool{}
+	taken := map[string]string{}
+	for _, trait := range traits {
+		category, ok := known[trait]
+		if !ok {
+			return domain.NewBadRequest(fmt.Sprintf("unknown trait %s", trait))
+		}
+		if seen[trait] {
+			return domain.NewBadRequest(fmt.Sprintf("trait %s is given twice", trait))
+		}
+		seen[trait] = true
+
+		if !category.Multiple {
+			if other, ok := taken[category.ID]; ok {
+				return domain.NewBadRequest(fmt.Sprintf("traits %s and %s are both %s, pick one", other, trait, strings.ToLower(category.Name)))
+			}
+			taken[category.ID] = trait
+		}
+	}
+
 	return nil
 }
+
+// traitCategoriesById maps every trait to its category.
+func traitCategoriesById(categories []domain.TraitCategory) map[string]*domain.TraitCategory {
+	res := map[string]*domain.TraitCategory{}
+	for i := range categories {
+		for _, trait := range categories[i].Traits {
+			res[trait.ID] = &categories[i]
+		}
+	}
+
+	return res
+}
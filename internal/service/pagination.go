@@ -0,0 +1,20 @@
+package service
+
+import (
+	"cats-social/internal/domain"
+	"slices"
+)
+
+// trimPage drops the extra row a listing reads to tell whether there are
+// more, and puts a page read backward in order again.
+func trimPage[T any](rows []T, page domain.PageRequest) ([]T, bool) {
+	hasMore := len(rows) > page.Limit
+	if hasMore {
+		rows = rows[:page.Limit]
+	}
+	if page.Cursor != nil && page.Cursor.Backward {
+		slices.Reverse(rows)
+	}
+
+	return rows, hasMore
+}
@@ -0,0 +1,18 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL,
+    refresh_token_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+ALTER TABLE sessions ADD CONSTRAINT fk_user_id_users FOREIGN KEY (user_id) REFERENCES users (id);
+
+ALTER TABLE sessions ADD CONSTRAINT unique_refresh_token_hash UNIQUE (refresh_token_hash);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL,
+    token_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+ALTER TABLE password_reset_tokens ADD CONSTRAINT fk_user_id_users FOREIGN KEY (user_id) REFERENCES users (id);
+
+ALTER TABLE password_reset_tokens ADD CONSTRAINT unique_token_hash UNIQUE (token_hash);
+
+COMMIT;
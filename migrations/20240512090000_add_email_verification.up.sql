@@ -0,0 +1,22 @@
+BEGIN;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- accounts registered before verification existed are trusted as they are
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id UUID PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL,
+    token_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+ALTER TABLE email_verification_tokens ADD CONSTRAINT fk_user_id_users FOREIGN KEY (user_id) REFERENCES users (id);
+
+ALTER TABLE email_verification_tokens ADD CONSTRAINT unique_email_verification_token_hash UNIQUE (token_hash);
+
+COMMIT;
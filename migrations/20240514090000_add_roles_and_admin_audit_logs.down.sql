@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP TABLE IF EXISTS admin_audit_logs;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS disabled_at;
+
+ALTER TABLE users
+DROP CONSTRAINT IF EXISTS role_check;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS role;
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+ALTER TABLE users ADD CONSTRAINT role_check CHECK (role IN ('user', 'admin'));
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS admin_audit_logs (
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    admin_id UUID NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    target_type VARCHAR(16) NOT NULL,
+    target_id UUID NOT NULL,
+    metadata JSONB NOT NULL DEFAULT '{}'
+);
+
+ALTER TABLE admin_audit_logs ADD CONSTRAINT fk_admin_id_users FOREIGN KEY (admin_id) REFERENCES users (id);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_logs_target ON admin_audit_logs (target_type, target_id);
+
+COMMIT;
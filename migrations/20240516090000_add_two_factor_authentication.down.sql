@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP TABLE IF EXISTS user_recovery_codes;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS totp_last_used_step;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS totp_enabled_at;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS totp_secret;
+
+COMMIT;
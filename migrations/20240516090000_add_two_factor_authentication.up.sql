@@ -0,0 +1,24 @@
+BEGIN;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS totp_last_used_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id UUID PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL,
+    code_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    used_at TIMESTAMPTZ
+);
+
+ALTER TABLE user_recovery_codes ADD CONSTRAINT fk_user_id_users FOREIGN KEY (user_id) REFERENCES users (id);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user_id ON user_recovery_codes (user_id);
+
+COMMIT;
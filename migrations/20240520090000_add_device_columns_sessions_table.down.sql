@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_seen_at;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS ip;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS device_name;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS user_agent;
+
+COMMIT;
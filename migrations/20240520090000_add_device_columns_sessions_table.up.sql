@@ -0,0 +1,15 @@
+BEGIN;
+
+ALTER TABLE sessions
+ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE sessions
+ADD COLUMN IF NOT EXISTS device_name VARCHAR(64) NOT NULL DEFAULT '';
+
+ALTER TABLE sessions
+ADD COLUMN IF NOT EXISTS ip VARCHAR(45) NOT NULL DEFAULT '';
+
+ALTER TABLE sessions
+ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS cat_images (
+    id UUID PRIMARY KEY NOT NULL,
+    cat_id UUID NOT NULL,
+    storage_key TEXT NOT NULL,
+    url TEXT NOT NULL,
+    content_type VARCHAR(32) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size_bytes INT NOT NULL,
+    position INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE cat_images ADD CONSTRAINT fk_cat_id_cats FOREIGN KEY (cat_id) REFERENCES cats (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_cat_images_cat_id ON cat_images (cat_id, position);
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_cat_images_variants_pending;
+
+ALTER TABLE cat_images DROP COLUMN IF EXISTS variants_error;
+
+ALTER TABLE cat_images DROP COLUMN IF EXISTS variants_next_attempt_at;
+
+ALTER TABLE cat_images DROP COLUMN IF EXISTS variants_attempts;
+
+ALTER TABLE cat_images DROP COLUMN IF EXISTS variants_status;
+
+ALTER TABLE cat_images DROP COLUMN IF EXISTS variants;
+
+COMMIT;
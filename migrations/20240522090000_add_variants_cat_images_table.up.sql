@@ -0,0 +1,21 @@
+BEGIN;
+
+ALTER TABLE cat_images
+ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE cat_images
+ADD COLUMN IF NOT EXISTS variants_status VARCHAR(16) NOT NULL DEFAULT 'pending';
+
+ALTER TABLE cat_images
+ADD COLUMN IF NOT EXISTS variants_attempts INT NOT NULL DEFAULT 0;
+
+ALTER TABLE cat_images
+ADD COLUMN IF NOT EXISTS variants_next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE cat_images
+ADD COLUMN IF NOT EXISTS variants_error TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_cat_images_variants_pending ON cat_images (variants_next_attempt_at)
+WHERE variants_status = 'pending';
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_cats_description_trgm;
+
+DROP INDEX IF EXISTS idx_cats_name_trgm;
+
+DROP INDEX IF EXISTS idx_cats_search_vector;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS search_vector;
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE cats
+ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', name), 'A') ||
+    setweight(to_tsvector('simple', description), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_cats_search_vector ON cats USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_cats_name_trgm ON cats USING GIN (name gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_cats_description_trgm ON cats USING GIN (description gin_trgm_ops);
+
+COMMIT;
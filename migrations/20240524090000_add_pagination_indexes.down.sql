@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_cat_matches_created_at_id;
+
+DROP INDEX IF EXISTS idx_cats_created_at_id;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_cats_created_at_id ON cats (created_at, id) WHERE deleted = false;
+
+CREATE INDEX IF NOT EXISTS idx_cat_matches_created_at_id ON cat_matches (created_at, id);
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_cats_latitude_longitude;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS city;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS longitude;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS latitude;
+
+COMMIT;
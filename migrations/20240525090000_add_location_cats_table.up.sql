@@ -0,0 +1,14 @@
+BEGIN;
+
+ALTER TABLE cats
+ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+
+ALTER TABLE cats
+ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+ALTER TABLE cats
+ADD COLUMN IF NOT EXISTS city VARCHAR(60);
+
+CREATE INDEX IF NOT EXISTS idx_cats_latitude_longitude ON cats (latitude, longitude) WHERE deleted = false AND latitude IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS cat_likes (
+    user_id UUID NOT NULL,
+    cat_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, cat_id)
+);
+
+ALTER TABLE cat_likes ADD CONSTRAINT fk_user_id_users FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE cat_likes ADD CONSTRAINT fk_cat_id_cats FOREIGN KEY (cat_id) REFERENCES cats (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_cat_likes_user_id_created_at ON cat_likes (user_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_cat_likes_cat_id_created_at ON cat_likes (cat_id, created_at);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS cat_traits;
+
+DROP TABLE IF EXISTS traits;
+
+DROP TABLE IF EXISTS trait_categories;
+
+COMMIT;
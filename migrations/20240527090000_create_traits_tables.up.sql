@@ -0,0 +1,67 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS trait_categories (
+    id VARCHAR(30) PRIMARY KEY NOT NULL,
+    name VARCHAR(60) NOT NULL,
+    multiple BOOLEAN NOT NULL,
+    position INT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS traits (
+    id VARCHAR(30) PRIMARY KEY NOT NULL,
+    category_id VARCHAR(30) NOT NULL,
+    name VARCHAR(60) NOT NULL,
+    position INT NOT NULL
+);
+
+ALTER TABLE traits ADD CONSTRAINT fk_category_id_trait_categories FOREIGN KEY (category_id) REFERENCES trait_categories (id);
+
+CREATE TABLE IF NOT EXISTS cat_traits (
+    cat_id UUID NOT NULL,
+    trait_id VARCHAR(30) NOT NULL,
+    PRIMARY KEY (cat_id, trait_id)
+);
+
+ALTER TABLE cat_traits ADD CONSTRAINT fk_cat_id_cats FOREIGN KEY (cat_id) REFERENCES cats (id) ON DELETE CASCADE;
+
+ALTER TABLE cat_traits ADD CONSTRAINT fk_trait_id_traits FOREIGN KEY (trait_id) REFERENCES traits (id);
+
+CREATE INDEX IF NOT EXISTS idx_cat_traits_trait_id ON cat_traits (trait_id, cat_id);
+
+INSERT INTO trait_categories (id, name, multiple, position) VALUES
+    ('temperament', 'Temperament', true, 1),
+    ('energy', 'Energy level', false, 2),
+    ('coat', 'Coat', false, 3),
+    ('health', 'Health tested', true, 4),
+    ('lifestyle', 'Indoor / outdoor', false, 5)
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO traits (id, category_id, name, position) VALUES
+    ('calm', 'temperament', 'Calm', 1),
+    ('playful', 'temperament', 'Playful', 2),
+    ('affectionate', 'temperament', 'Affectionate', 3),
+    ('curious', 'temperament', 'Curious', 4),
+    ('independent', 'temperament', 'Independent', 5),
+    ('shy', 'temperament', 'Shy', 6),
+    ('vocal', 'temperament', 'Vocal', 7),
+    ('good-with-kids', 'temperament', 'Good with kids', 8),
+    ('good-with-cats', 'temperament', 'Good with other cats', 9),
+    ('good-with-dogs', 'temperament', 'Good with dogs', 10),
+    ('energy-low', 'energy', 'Low energy', 11),
+    ('energy-medium', 'energy', 'Medium energy', 12),
+    ('energy-high', 'energy', 'High energy', 13),
+    ('coat-hairless', 'coat', 'Hairless', 14),
+    ('coat-short', 'coat', 'Short hair', 15),
+    ('coat-medium', 'coat', 'Medium hair', 16),
+    ('coat-long', 'coat', 'Long hair', 17),
+    ('vaccinated', 'health', 'Vaccinated', 18),
+    ('neutered', 'health', 'Neutered / spayed', 19),
+    ('fiv-felv-tested', 'health', 'FIV / FeLV tested', 20),
+    ('pkd-tested', 'health', 'PKD tested', 21),
+    ('hcm-screened', 'health', 'HCM screened', 22),
+    ('indoor', 'lifestyle', 'Indoor', 23),
+    ('outdoor', 'lifestyle', 'Outdoor', 24),
+    ('indoor-outdoor', 'lifestyle', 'Indoor and outdoor', 25)
+ON CONFLICT (id) DO NOTHING;
+
+COMMIT;
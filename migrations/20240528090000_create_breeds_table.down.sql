@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE cats DROP CONSTRAINT IF EXISTS fk_race_breeds;
+
+DROP TABLE IF EXISTS breeds;
+
+COMMIT;
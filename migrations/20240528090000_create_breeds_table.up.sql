@@ -0,0 +1,40 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS breeds (
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    name VARCHAR(24) NOT NULL,
+    aliases TEXT[] NOT NULL DEFAULT '{}',
+    size VARCHAR(8),
+    coat VARCHAR(8),
+    origin VARCHAR(60) NOT NULL DEFAULT ''
+);
+
+ALTER TABLE breeds ADD CONSTRAINT breeds_name_unique UNIQUE (name);
+
+ALTER TABLE breeds ADD CONSTRAINT size_check CHECK (size IN ('small', 'medium', 'large'));
+
+ALTER TABLE breeds ADD CONSTRAINT coat_check CHECK (coat IN ('hairless', 'short', 'medium', 'long'));
+
+INSERT INTO breeds (id, name, aliases, size, coat, origin) VALUES
+    (gen_random_uuid(), 'Persian', '{"Persian Longhair"}', 'medium', 'long', 'Iran'),
+    (gen_random_uuid(), 'Maine Coon', '{"Maine Coon Cat", "Coon Cat"}', 'large', 'long', 'United States'),
+    (gen_random_uuid(), 'Siamese', '{"Meezer"}', 'medium', 'short', 'Thailand'),
+    (gen_random_uuid(), 'Ragdoll', '{"Rag Doll"}', 'large', 'long', 'United States'),
+    (gen_random_uuid(), 'Bengal', '{}', 'medium', 'short', 'United States'),
+    (gen_random_uuid(), 'Sphynx', '{"Sphinx", "Canadian Hairless"}', 'medium', 'hairless', 'Canada'),
+    (gen_random_uuid(), 'British Shorthair', '{"BSH", "British Blue"}', 'medium', 'short', 'United Kingdom'),
+    (gen_random_uuid(), 'Abyssinian', '{"Aby"}', 'medium', 'short', 'Ethiopia'),
+    (gen_random_uuid(), 'Scottish Fold', '{"Fold"}', 'medium', 'short', 'United Kingdom'),
+    (gen_random_uuid(), 'Birman', '{"Sacred Cat of Burma"}', 'medium', 'long', 'Myanmar')
+ON CONFLICT (name) DO NOTHING;
+
+-- races stored before the catalogue existed keep working, without metadata
+INSERT INTO breeds (id, name)
+SELECT gen_random_uuid(), race
+FROM (SELECT DISTINCT race FROM cats) legacy
+ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE cats ADD CONSTRAINT fk_race_breeds FOREIGN KEY (race) REFERENCES breeds (name) ON UPDATE CASCADE;
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_cats_dam_id;
+
+DROP INDEX IF EXISTS idx_cats_sire_id;
+
+ALTER TABLE cats DROP CONSTRAINT IF EXISTS parents_check;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS dam_id;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS sire_id;
+
+COMMIT;
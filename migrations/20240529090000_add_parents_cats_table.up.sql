@@ -0,0 +1,19 @@
+BEGIN;
+
+ALTER TABLE cats
+ADD COLUMN IF NOT EXISTS sire_id UUID;
+
+ALTER TABLE cats
+ADD COLUMN IF NOT EXISTS dam_id UUID;
+
+ALTER TABLE cats ADD CONSTRAINT fk_sire_id_cats FOREIGN KEY (sire_id) REFERENCES cats (id) ON DELETE SET NULL;
+
+ALTER TABLE cats ADD CONSTRAINT fk_dam_id_cats FOREIGN KEY (dam_id) REFERENCES cats (id) ON DELETE SET NULL;
+
+ALTER TABLE cats ADD CONSTRAINT parents_check CHECK (sire_id <> id AND dam_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_cats_sire_id ON cats (sire_id) WHERE sire_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_cats_dam_id ON cats (dam_id) WHERE dam_id IS NOT NULL;
+
+COMMIT;
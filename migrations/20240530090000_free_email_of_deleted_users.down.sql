@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS unique_email;
+
+-- fails while a deleted account and a live one share an email
+ALTER TABLE users ADD CONSTRAINT unique_email UNIQUE (email);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS unique_email;
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_email ON users (email) WHERE deleted_at IS NULL;
+
+COMMIT;
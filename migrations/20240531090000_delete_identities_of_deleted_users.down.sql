@@ -0,0 +1 @@
+-- the deleted identities cannot be brought back
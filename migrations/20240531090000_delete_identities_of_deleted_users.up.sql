@@ -0,0 +1,7 @@
+BEGIN;
+
+-- accounts deleted before identities were removed with them
+DELETE FROM user_identities
+WHERE user_id IN (SELECT id FROM users WHERE deleted_at IS NOT NULL);
+
+COMMIT;
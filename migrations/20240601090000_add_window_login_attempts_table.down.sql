@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_login_attempts_last_failure_at;
+
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS window_seconds;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE login_attempts
+ADD COLUMN IF NOT EXISTS window_seconds DOUBLE PRECISION NOT NULL DEFAULT 900;
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure_at ON login_attempts (last_failure_at);
+
+COMMIT;